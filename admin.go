@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bot/i18n"
+	"bot/queue"
+	"bot/storage"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/telebot.v3"
+)
+
+// permanentBan is how long a ban without an explicit duration lasts.
+const permanentBan = 100 * 365 * 24 * time.Hour
+
+// adminOnly rejects updates from users that are not in adminIDs.
+func adminOnly(adminIDs []int64) telebot.MiddlewareFunc {
+	admins := make(map[int64]bool, len(adminIDs))
+	for _, id := range adminIDs {
+		admins[id] = true
+	}
+
+	return func(next telebot.HandlerFunc) telebot.HandlerFunc {
+		return func(c telebot.Context) error {
+			user := c.Sender()
+			if user == nil || !admins[user.ID] {
+				if user != nil {
+					logInfo("User %d (@%s) tried admin command: %s", user.ID, user.Username, c.Text())
+				}
+				return c.Send(tr(c, i18n.AdminOnly))
+			}
+			logInfo("Admin %d (@%s) ran: %s", user.ID, user.Username, c.Text())
+			return next(c)
+		}
+	}
+}
+
+// parseBanDuration accepts Go durations plus a "d" suffix for days.
+func parseBanDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// registerAdminCommands adds the admin-only commands to bot behind the
+// adminOnly middleware.
+func registerAdminCommands(bot *telebot.Bot, jobs *queue.Queue, adminIDs []int64) {
+	admin := bot.Group()
+	admin.Use(adminOnly(adminIDs))
+
+	// Admin command to set Instagram cookies
+	admin.Handle("/setcookies", func(c telebot.Context) error {
+		// Get cookie content from message
+		cookieText := c.Message().Payload
+		if cookieText == "" {
+			return c.Send(tr(c, i18n.SetCookiesUsage))
+		}
+
+		// Save to cookie file
+		if err := os.WriteFile(cfg.InstagramCookieFile, []byte(cookieText), 0644); err != nil {
+			logError("Failed to write cookie file: %v", err)
+			return c.Send(tr(c, i18n.SetCookiesFailed))
+		}
+
+		logInfo("Instagram cookies updated successfully")
+		return c.Send(tr(c, i18n.SetCookiesDone))
+	})
+
+	// Admin command to drop cached Telegram file IDs
+	admin.Handle("/purgecache", func(c telebot.Context) error {
+		// An optional argument limits the purge to matching URLs
+		match := strings.TrimSpace(c.Message().Payload)
+		removed, err := fileCache.Purge(match)
+		if err != nil {
+			logError("Failed to purge file cache: %v", err)
+			return c.Send(tr(c, i18n.PurgeFailed))
+		}
+
+		logInfo("Purged %d file cache entries (match %q)", removed, match)
+		return c.Send(tr(c, i18n.PurgeDone, removed))
+	})
+
+	admin.Handle("/stats", func(c telebot.Context) error {
+		now := time.Now()
+		var b strings.Builder
+		b.WriteString(tr(c, i18n.StatsTitle))
+
+		for _, period := range []struct {
+			name  i18n.Key
+			since time.Time
+		}{
+			{i18n.StatsDay, now.Add(-24 * time.Hour)},
+			{i18n.StatsWeek, now.Add(-7 * 24 * time.Hour)},
+			{i18n.StatsAll, time.Time{}},
+		} {
+			st, err := store.Stats(period.since)
+			if err != nil {
+				logError("Failed to read stats: %v", err)
+				return c.Send(tr(c, i18n.StatsFailed))
+			}
+			b.WriteString(tr(c, i18n.StatsPeriod, tr(c, period.name),
+				st.Users, st.NewUsers, st.ActiveUsers, st.Requests,
+				st.Downloads, float64(st.Bytes)/1024/1024, st.Failures))
+		}
+
+		bans, err := store.Bans(now)
+		if err != nil {
+			logError("Failed to read bans: %v", err)
+		}
+		b.WriteString(tr(c, i18n.StatsFooter, len(bans), jobs.Len(), len(jobs.Active()), fileCache.Len()))
+		return c.Send(b.String())
+	})
+
+	admin.Handle("/ban", func(c telebot.Context) error {
+		args := c.Args()
+		if len(args) < 1 || len(args) > 2 {
+			return c.Send(tr(c, i18n.BanUsage))
+		}
+
+		userID, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return c.Send(tr(c, i18n.BadUserID))
+		}
+
+		duration := permanentBan
+		if len(args) == 2 {
+			if duration, err = parseBanDuration(args[1]); err != nil {
+				return c.Send(tr(c, i18n.BadDuration))
+			}
+		}
+
+		ban := storage.Ban{UserID: userID, Until: time.Now().Add(duration), Reason: fmt.Sprintf("admin %d", c.Sender().ID)}
+		if err := store.SetBan(ban); err != nil {
+			logError("Failed to ban User %d: %v", userID, err)
+			return c.Send(tr(c, i18n.BanFailed))
+		}
+
+		logInfo("User %d banned until %s", userID, ban.Until.Format(time.RFC3339))
+		if duration == permanentBan {
+			return c.Send(tr(c, i18n.BannedForever, userID))
+		}
+		return c.Send(tr(c, i18n.BannedUntil, userID, ban.Until.In(cfg.Location).Format("2006-01-02 15:04")))
+	})
+
+	admin.Handle("/unban", func(c telebot.Context) error {
+		args := c.Args()
+		if len(args) != 1 {
+			return c.Send(tr(c, i18n.UnbanUsage))
+		}
+
+		userID, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return c.Send(tr(c, i18n.BadUserID))
+		}
+
+		if err := store.DeleteBan(userID); err != nil {
+			logError("Failed to unban User %d: %v", userID, err)
+			return c.Send(tr(c, i18n.UnbanFailed))
+		}
+
+		mutex.Lock()
+		delete(bannedUsers, userID)
+		delete(userRequests, userID)
+		mutex.Unlock()
+
+		logInfo("User %d unbanned", userID)
+		return c.Send(tr(c, i18n.Unbanned, userID))
+	})
+
+	admin.Handle("/user", func(c telebot.Context) error {
+		args := c.Args()
+		if len(args) != 1 {
+			return c.Send(tr(c, i18n.UserUsage))
+		}
+
+		userID, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return c.Send(tr(c, i18n.BadUserID))
+		}
+
+		u, err := store.User(userID)
+		if errors.Is(err, storage.ErrNotFound) {
+			return c.Send(tr(c, i18n.UserNotFound))
+		}
+		if err != nil {
+			logError("Failed to read User %d: %v", userID, err)
+			return c.Send(tr(c, i18n.UserFailed))
+		}
+
+		const layout = "2006-01-02 15:04"
+		var b strings.Builder
+		b.WriteString(tr(c, i18n.UserInfo, u.ID, u.Username, u.FirstName, u.LastName,
+			u.FirstSeen.In(cfg.Location).Format(layout), u.LastSeen.In(cfg.Location).Format(layout),
+			u.Requests, u.Language))
+
+		if ban, err := store.Ban(userID); err == nil && ban.Active(time.Now()) {
+			b.WriteString(tr(c, i18n.UserBanned, ban.Until.In(cfg.Location).Format(layout), ban.Reason))
+		} else {
+			b.WriteString(tr(c, i18n.UserNotBanned))
+		}
+
+		reqs, err := store.UserRequests(userID, 10)
+		if err != nil {
+			logError("Failed to read requests of User %d: %v", userID, err)
+		}
+		if len(reqs) > 0 {
+			b.WriteString(tr(c, i18n.UserRecent))
+			for _, r := range reqs {
+				fmt.Fprintf(&b, "%s [%s] %s\n", r.Time.In(cfg.Location).Format("01-02 15:04"), r.Service, r.URL)
+			}
+		}
+		return c.Send(b.String(), telebot.NoPreview)
+	})
+
+	admin.Handle("/queue", func(c telebot.Context) error {
+		active := jobs.Active()
+		pending := jobs.Pending()
+		if len(active) == 0 && len(pending) == 0 {
+			return c.Send(tr(c, i18n.QueueEmpty))
+		}
+
+		var b strings.Builder
+		b.WriteString(tr(c, i18n.QueueActiveTitle, len(active)))
+		for _, j := range active {
+			fmt.Fprintf(&b, "#%d %s user %d, %s, %s\n", j.ID, j.State(), j.UserID, time.Since(j.Started()).Round(time.Second), j.URL)
+		}
+		b.WriteString(tr(c, i18n.QueuePendingTitle, len(pending)))
+		for i, j := range pending {
+			fmt.Fprintf(&b, "%d. #%d user %d, %s\n", i+1, j.ID, j.UserID, j.URL)
+		}
+		return c.Send(b.String(), telebot.NoPreview)
+	})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bot/i18n"
+	"strconv"
+
+	"gopkg.in/telebot.v3"
+)
+
+// cancelButton routes taps on a status message's Cancel button to
+// handleCancelButton.
+var cancelButton = &telebot.Btn{Unique: "cancel"}
+
+// cancelMarkup is the keyboard shown under the status message of job id.
+func cancelMarkup(c telebot.Context, id int64) *telebot.ReplyMarkup {
+	markup := &telebot.ReplyMarkup{}
+	markup.Inline(markup.Row(markup.Data(tr(c, i18n.CancelButton), cancelButton.Unique, strconv.FormatInt(id, 10))))
+	return markup
+}
+
+// handleCancel stops all of the sender's waiting and running downloads.
+func handleCancel(c telebot.Context) error {
+	user := c.Sender()
+	n := jobs.CancelUser(user.ID)
+	logInfo("User %d (@%s) cancelled %d jobs", user.ID, user.Username, n)
+	if n == 0 {
+		return c.Send(tr(c, i18n.CancelNothing))
+	}
+	return c.Send(tr(c, i18n.CancelDone, n))
+}
+
+// handleCancelButton stops the job whose status message was tapped.
+func handleCancelButton(c telebot.Context) error {
+	args := c.Args()
+	if len(args) != 1 {
+		return c.Respond()
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return c.Respond()
+	}
+
+	job := jobs.Job(id)
+	if job != nil && job.UserID != c.Sender().ID {
+		return c.Respond(&telebot.CallbackResponse{Text: tr(c, i18n.PickNotYours)})
+	}
+	if job == nil || !jobs.Cancel(id) {
+		return c.Respond(&telebot.CallbackResponse{Text: tr(c, i18n.CancelTooLate)})
+	}
+
+	logInfo("User %d (@%s) cancelled job %d", c.Sender().ID, c.Sender().Username, id)
+	return c.Respond()
+}
@@ -1,22 +1,181 @@
 package config
 
 import (
+	"bot/i18n"
+	"bot/logging"
+	"bot/services"
+	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/joho/godotenv"
 )
 
+// DefaultPath is where NewConfig looks for the YAML file unless CONFIG_PATH
+// is set.
+const DefaultPath = "./config/config.yml"
+
 type (
 	Config struct {
 		TelegramApi `yaml:"telegramapi"`
+		Webhook     `yaml:"webhook"`
+		Admin       `yaml:"admin"`
+		Bot         `yaml:"bot"`
+		RateLimit   `yaml:"ratelimit"`
+		Queue       `yaml:"queue"`
+		Storage     `yaml:"storage"`
+		Downloads   `yaml:"downloads"`
+		Audio       `yaml:"audio"`
+		Picker      `yaml:"picker"`
+		Limits      `yaml:"limits"`
+		Log         `yaml:"log"`
+		HTTP        `yaml:"http"`
+
+		// Services are the sites the bot recognises, tried in order. Left
+		// empty, services.Defaults is used.
+		Services []services.Service `yaml:"services" env:"-"`
 	}
 
 	TelegramApi struct {
-		TelegramToken string `env-required:"true" yaml:"telegramtoken" env:"TELEGRAMTOKEN"`
+		TelegramToken string        `env-required:"true" yaml:"telegramtoken" env:"TELEGRAMTOKEN"`
+		Mode          string        `yaml:"mode" env:"BOT_MODE" env-default:"polling"`
+		PollTimeout   time.Duration `yaml:"poll_timeout" env:"POLL_TIMEOUT" env-default:"12s"`
+	}
+
+	Webhook struct {
+		WebhookListen     string `yaml:"listen" env:"WEBHOOK_LISTEN" env-default:":8443"`
+		WebhookURL        string `yaml:"public_url" env:"WEBHOOK_URL"`
+		WebhookCert       string `yaml:"cert" env:"WEBHOOK_CERT"`
+		WebhookKey        string `yaml:"key" env:"WEBHOOK_KEY"`
+		WebhookSelfSigned bool   `yaml:"self_signed" env:"WEBHOOK_SELF_SIGNED"`
+		WebhookSecret     string `yaml:"secret_token" env:"WEBHOOK_SECRET"`
+	}
+
+	Admin struct {
+		AdminIDs []int64 `yaml:"ids" env:"ADMIN_IDS" env-separator:","`
+	}
+
+	Bot struct {
+		Timezone        string `yaml:"timezone" env:"TIMEZONE" env-default:"Asia/Tashkent"`
+		Caption         string `yaml:"caption" env:"CAPTION" env-default:"✨ @media_download_any_bot orqali yuklab olindi"`
+		DefaultLanguage string `yaml:"default_language" env:"DEFAULT_LANGUAGE" env-default:"uz"`
+		MaxURLs         int    `yaml:"max_urls" env:"MAX_URLS" env-default:"5"`
+
+		// Short links are followed through at most ResolveMaxHops
+		// redirects within ResolveTimeout.
+		ResolveMaxHops int           `yaml:"resolve_max_hops" env:"RESOLVE_MAX_HOPS" env-default:"5"`
+		ResolveTimeout time.Duration `yaml:"resolve_timeout" env:"RESOLVE_TIMEOUT" env-default:"10s"`
+
+		// StatusInterval is the least time between two edits of status
+		// messages in the same chat.
+		StatusInterval time.Duration `yaml:"status_interval" env:"STATUS_INTERVAL" env-default:"3s"`
+
+		// Location is Timezone resolved by Validate.
+		Location *time.Location `yaml:"-" env:"-"`
+	}
+
+	RateLimit struct {
+		RequestLimit    int           `yaml:"request_limit" env:"REQUEST_LIMIT" env-default:"3"`
+		BanLimit        int           `yaml:"ban_limit" env:"BAN_LIMIT" env-default:"5"`
+		RateLimitWindow time.Duration `yaml:"window" env:"RATE_LIMIT_WINDOW" env-default:"5s"`
+		BanDuration     time.Duration `yaml:"ban_duration" env:"BAN_DURATION" env-default:"30s"`
+	}
+
+	Queue struct {
+		Workers   int `yaml:"workers" env:"QUEUE_WORKERS" env-default:"3"`
+		QueueSize int `yaml:"size" env:"QUEUE_SIZE" env-default:"30"`
+
+		// ShutdownGrace is how long running jobs may finish after SIGTERM.
+		ShutdownGrace time.Duration `yaml:"shutdown_grace" env:"SHUTDOWN_GRACE" env-default:"60s"`
+	}
+
+	Storage struct {
+		StorePath     string        `yaml:"path" env:"STORE_PATH" env-default:"data/bot.db"`
+		FileCacheTTL  time.Duration `yaml:"file_cache_ttl" env:"FILE_CACHE_TTL" env-default:"168h"`
+		FileCacheSize int           `yaml:"file_cache_size" env:"FILE_CACHE_SIZE" env-default:"10000"`
+	}
+
+	Downloads struct {
+		DownloadsDir        string `yaml:"dir" env:"DOWNLOADS_DIR" env-default:"downloads"`
+		InstagramCookieFile string `yaml:"instagram_cookie_file" env:"INSTAGRAM_COOKIE_FILE" env-default:"instagram_cookies.txt"`
+		RapidAPIKey         string `yaml:"rapidapi_key" env:"RAPIDAPI_KEY"`
+
+		// JobTimeout bounds each download, conversion included.
+		JobTimeout time.Duration `yaml:"job_timeout" env:"JOB_TIMEOUT" env-default:"10m"`
+
+		// The janitor removes leftover job directories older than
+		// DownloadsMaxAge and keeps the directory under DownloadsQuotaMB
+		// (0 for no quota).
+		JanitorInterval  time.Duration `yaml:"janitor_interval" env:"JANITOR_INTERVAL" env-default:"10m"`
+		DownloadsMaxAge  time.Duration `yaml:"max_age" env:"DOWNLOADS_MAX_AGE" env-default:"1h"`
+		DownloadsQuotaMB int64         `yaml:"quota_mb" env:"DOWNLOADS_QUOTA_MB" env-default:"10240"`
+	}
+
+	Audio struct {
+		AudioCodec   string `yaml:"codec" env:"AUDIO_CODEC" env-default:"mp3"`
+		AudioBitrate int    `yaml:"bitrate" env:"AUDIO_BITRATE" env-default:"192"`
+	}
+
+	Picker struct {
+		PickerServices []string      `yaml:"services" env:"PICKER_SERVICES" env-separator:"," env-default:"YouTube"`
+		PickerHeights  []int         `yaml:"heights" env:"PICKER_HEIGHTS" env-separator:"," env-default:"360,720,1080"`
+		PickerTTL      time.Duration `yaml:"ttl" env:"PICKER_TTL" env-default:"5m"`
+		ProbeTimeout   time.Duration `yaml:"probe_timeout" env:"PROBE_TIMEOUT" env-default:"30s"`
+
+		// ProbeWorkers bounds how many probes run at once, outside the
+		// download queue.
+		ProbeWorkers int `yaml:"probe_workers" env:"PROBE_WORKERS" env-default:"2"`
+	}
+
+	Limits struct {
+		// Links of PreflightServices are probed before they are queued.
+		// Live streams, and media longer than MaxDuration or larger than
+		// MaxFileSizeMB, are turned down (0 for no limit). Downloads of
+		// every service also stop once a file grows past MaxFileSizeMB.
+		PreflightServices []string      `yaml:"preflight_services" env:"PREFLIGHT_SERVICES" env-separator:"," env-default:"YouTube"`
+		MaxDuration       time.Duration `yaml:"max_duration" env:"MAX_DURATION" env-default:"1h"`
+		MaxFileSizeMB     int64         `yaml:"max_file_size_mb" env:"MAX_FILE_SIZE_MB" env-default:"50"`
+	}
+
+	HTTP struct {
+		// HTTPListen is where /metrics, /healthz and /readyz are served;
+		// empty turns them off.
+		HTTPListen string `yaml:"listen" env:"HTTP_LISTEN" env-default:":9090"`
+
+		HealthInterval time.Duration `yaml:"health_interval" env:"HEALTH_INTERVAL" env-default:"1m"`
+		HealthTimeout  time.Duration `yaml:"health_timeout" env:"HEALTH_TIMEOUT" env-default:"10s"`
+		MinFreeDiskMB  uint64        `yaml:"min_free_disk_mb" env:"MIN_FREE_DISK_MB" env-default:"1024"`
+	}
+
+	Log struct {
+		LogLevel      string        `yaml:"level" env:"LOG_LEVEL" env-default:"info"`
+		LogFormat     string        `yaml:"format" env:"LOG_FORMAT" env-default:"text"`
+		LogFile       string        `yaml:"file" env:"LOG_FILE" env-default:"logs/bot.log"`
+		LogMaxSizeMB  int           `yaml:"max_size_mb" env:"LOG_MAX_SIZE_MB" env-default:"50"`
+		LogMaxAge     time.Duration `yaml:"max_age" env:"LOG_MAX_AGE" env-default:"720h"`
+		LogMaxBackups int           `yaml:"max_backups" env:"LOG_MAX_BACKUPS" env-default:"5"`
 	}
 )
 
+// Ways the bot can receive updates from Telegram.
+const (
+	ModePolling = "polling"
+	ModeWebhook = "webhook"
+)
+
+// secretTokenRe is the character set Telegram allows in a webhook secret.
+var secretTokenRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// Audio bitrates users may ask for, in kbps.
+const (
+	MinAudioBitrate = 32
+	MaxAudioBitrate = 320
+)
+
 func NewConfig() (*Config, error) {
 	err := godotenv.Load()
 	if err != nil {
@@ -25,16 +184,118 @@ func NewConfig() (*Config, error) {
 
 	cfg := &Config{}
 
-	// // YAML fayldan o‘qish (agar mavjud bo‘lsa)
-	// err = cleanenv.ReadConfig("./config/config.yml", cfg)
-	// if err != nil {
-	// 	fmt.Println("⚠️  Ogohlantirish: config.yml fayli topilmadi yoki o‘qib bo‘lmadi.")
-	// }
+	path := os.Getenv("CONFIG_PATH")
+	if path == "" {
+		path = DefaultPath
+	}
 
-	err = cleanenv.ReadEnv(cfg)
+	// YAML fayldan o‘qish (agar mavjud bo‘lsa), env qiymatlari ustun turadi
+	if _, statErr := os.Stat(path); statErr == nil {
+		err = cleanenv.ReadConfig(path, cfg)
+	} else {
+		fmt.Printf("⚠️  Ogohlantirish: %s fayli topilmadi, faqat env ishlatiladi.\n", path)
+		err = cleanenv.ReadEnv(cfg)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("config error: %w", err)
 	}
 
+	if len(cfg.Services) == 0 {
+		cfg.Services = services.Defaults(cfg.InstagramCookieFile)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config error: %w", err)
+	}
+
 	return cfg, nil
 }
+
+// Validate checks every value and resolves Location. All problems are
+// reported at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, v ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, v...))
+		}
+	}
+
+	check(c.TelegramToken != "", "telegramapi.telegramtoken (TELEGRAMTOKEN) must be set")
+	check(c.PollTimeout > 0, "telegramapi.poll_timeout (POLL_TIMEOUT) must be positive, got %s", c.PollTimeout)
+	check(c.Mode == ModePolling || c.Mode == ModeWebhook, "telegramapi.mode (BOT_MODE) must be %s or %s, got %q", ModePolling, ModeWebhook, c.Mode)
+
+	if c.Mode == ModeWebhook {
+		u, err := url.Parse(c.WebhookURL)
+		check(err == nil && u.Scheme == "https" && u.Host != "", "webhook.public_url (WEBHOOK_URL) must be an https URL, got %q", c.WebhookURL)
+		check(c.WebhookListen != "", "webhook.listen (WEBHOOK_LISTEN) must be set")
+		check((c.WebhookCert == "") == (c.WebhookKey == ""), "webhook.cert (WEBHOOK_CERT) and webhook.key (WEBHOOK_KEY) must be set together")
+		check(!c.WebhookSelfSigned || c.WebhookCert != "", "webhook.self_signed (WEBHOOK_SELF_SIGNED) needs webhook.cert (WEBHOOK_CERT)")
+		check(c.HTTPListen == "" || c.HTTPListen != c.WebhookListen, "http.listen (HTTP_LISTEN) must differ from webhook.listen (WEBHOOK_LISTEN)")
+		check(c.WebhookSecret == "" || secretTokenRe.MatchString(c.WebhookSecret), "webhook.secret_token (WEBHOOK_SECRET) must be 1-256 characters of A-Z, a-z, 0-9, _ and -")
+	}
+
+	for _, id := range c.AdminIDs {
+		check(id > 0, "admin.ids (ADMIN_IDS) must be positive Telegram user IDs, got %d", id)
+	}
+
+	loc, err := time.LoadLocation(c.Timezone)
+	check(err == nil, "bot.timezone (TIMEZONE) %q is not a known time zone", c.Timezone)
+	c.Location = loc
+	_, ok := i18n.Parse(c.DefaultLanguage)
+	check(ok, "bot.default_language (DEFAULT_LANGUAGE) must be one of uz, ru, en, got %q", c.DefaultLanguage)
+	check(c.MaxURLs > 0, "bot.max_urls (MAX_URLS) must be positive, got %d", c.MaxURLs)
+	check(c.ResolveMaxHops > 0, "bot.resolve_max_hops (RESOLVE_MAX_HOPS) must be positive, got %d", c.ResolveMaxHops)
+	check(c.ResolveTimeout > 0, "bot.resolve_timeout (RESOLVE_TIMEOUT) must be positive, got %s", c.ResolveTimeout)
+	check(c.StatusInterval > 0, "bot.status_interval (STATUS_INTERVAL) must be positive, got %s", c.StatusInterval)
+	check(len([]rune(c.Caption)) <= 1024, "bot.caption (CAPTION) is longer than Telegram's 1024 character limit")
+
+	check(c.RequestLimit > 0, "ratelimit.request_limit (REQUEST_LIMIT) must be positive, got %d", c.RequestLimit)
+	check(c.BanLimit >= c.RequestLimit, "ratelimit.ban_limit (BAN_LIMIT) must be at least request_limit (%d), got %d", c.RequestLimit, c.BanLimit)
+	check(c.RateLimitWindow > 0, "ratelimit.window (RATE_LIMIT_WINDOW) must be positive, got %s", c.RateLimitWindow)
+	check(c.BanDuration > 0, "ratelimit.ban_duration (BAN_DURATION) must be positive, got %s", c.BanDuration)
+
+	check(c.Workers > 0, "queue.workers (QUEUE_WORKERS) must be positive, got %d", c.Workers)
+	check(c.QueueSize > 0, "queue.size (QUEUE_SIZE) must be positive, got %d", c.QueueSize)
+	check(c.ShutdownGrace >= 0, "queue.shutdown_grace (SHUTDOWN_GRACE) must not be negative, got %s", c.ShutdownGrace)
+
+	check(c.StorePath != "", "storage.path (STORE_PATH) must be set")
+	check(c.FileCacheTTL >= 0, "storage.file_cache_ttl (FILE_CACHE_TTL) must not be negative, got %s", c.FileCacheTTL)
+	check(c.FileCacheSize >= 0, "storage.file_cache_size (FILE_CACHE_SIZE) must not be negative, got %d", c.FileCacheSize)
+
+	check(c.DownloadsDir != "", "downloads.dir (DOWNLOADS_DIR) must be set")
+	check(c.InstagramCookieFile != "", "downloads.instagram_cookie_file (INSTAGRAM_COOKIE_FILE) must be set")
+	check(c.JobTimeout > 0, "downloads.job_timeout (JOB_TIMEOUT) must be positive, got %s", c.JobTimeout)
+	check(c.JanitorInterval > 0, "downloads.janitor_interval (JANITOR_INTERVAL) must be positive, got %s", c.JanitorInterval)
+	check(c.DownloadsMaxAge > c.JobTimeout, "downloads.max_age (DOWNLOADS_MAX_AGE) must be longer than job_timeout (%s), got %s", c.JobTimeout, c.DownloadsMaxAge)
+	check(c.DownloadsQuotaMB >= 0, "downloads.quota_mb (DOWNLOADS_QUOTA_MB) must not be negative, got %d", c.DownloadsQuotaMB)
+
+	check(c.AudioCodec == "mp3" || c.AudioCodec == "m4a", "audio.codec (AUDIO_CODEC) must be mp3 or m4a, got %q", c.AudioCodec)
+	check(c.AudioBitrate >= MinAudioBitrate && c.AudioBitrate <= MaxAudioBitrate, "audio.bitrate (AUDIO_BITRATE) must be between %d and %d kbps, got %d", MinAudioBitrate, MaxAudioBitrate, c.AudioBitrate)
+
+	for _, h := range c.PickerHeights {
+		check(h > 0, "picker.heights (PICKER_HEIGHTS) must be positive, got %d", h)
+	}
+	check(c.PickerTTL > 0, "picker.ttl (PICKER_TTL) must be positive, got %s", c.PickerTTL)
+	check(c.ProbeTimeout > 0, "picker.probe_timeout (PROBE_TIMEOUT) must be positive, got %s", c.ProbeTimeout)
+	check(c.ProbeWorkers > 0, "picker.probe_workers (PROBE_WORKERS) must be positive, got %d", c.ProbeWorkers)
+
+	check(c.MaxDuration >= 0, "limits.max_duration (MAX_DURATION) must not be negative, got %s", c.MaxDuration)
+	check(c.MaxFileSizeMB >= 0, "limits.max_file_size_mb (MAX_FILE_SIZE_MB) must not be negative, got %d", c.MaxFileSizeMB)
+
+	check(c.HealthInterval > 0, "http.health_interval (HEALTH_INTERVAL) must be positive, got %s", c.HealthInterval)
+	check(c.HealthTimeout > 0, "http.health_timeout (HEALTH_TIMEOUT) must be positive, got %s", c.HealthTimeout)
+
+	if _, err := services.New(c.Services); err != nil {
+		errs = append(errs, fmt.Errorf("services: %w", err))
+	}
+
+	_, err = logging.ParseLevel(c.LogLevel)
+	check(err == nil, "log.level (LOG_LEVEL) must be debug, info, warn or error, got %q", c.LogLevel)
+	check(c.LogFormat == logging.Text || c.LogFormat == logging.JSON, "log.format (LOG_FORMAT) must be text or json, got %q", c.LogFormat)
+	check(c.LogMaxSizeMB > 0, "log.max_size_mb (LOG_MAX_SIZE_MB) must be positive, got %d", c.LogMaxSizeMB)
+	check(c.LogMaxAge >= 0, "log.max_age (LOG_MAX_AGE) must not be negative, got %s", c.LogMaxAge)
+	check(c.LogMaxBackups >= 0, "log.max_backups (LOG_MAX_BACKUPS) must not be negative, got %d", c.LogMaxBackups)
+
+	return errors.Join(errs...)
+}
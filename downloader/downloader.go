@@ -0,0 +1,232 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrNoBackend is returned when no downloader is registered for a service.
+var ErrNoBackend = errors.New("downloader: no backend registered")
+
+// ErrProbeUnsupported is returned by backends that cannot read metadata
+// without downloading.
+var ErrProbeUnsupported = errors.New("downloader: probe not supported")
+
+// Logger is the subset of the bot logger the backends write to.
+type Logger interface {
+	Debugf(format string, v ...interface{})
+	Infof(format string, v ...interface{})
+	Errorf(format string, v ...interface{})
+}
+
+// ErrNoFiles is returned when a backend finished without producing any
+// media.
+var ErrNoFiles = errors.New("downloader: no files downloaded")
+
+// ErrFormatUnsupported is returned by backends that cannot produce the
+// requested format.
+var ErrFormatUnsupported = errors.New("downloader: format not supported")
+
+// Audio codecs supported for audio-only downloads.
+const (
+	MP3 = "mp3"
+	M4A = "m4a"
+)
+
+// Format selects what a backend should produce. The zero value is the
+// default video download.
+type Format struct {
+	// Height limits the video to at most this many lines, 0 for the best.
+	Height int
+
+	// Audio extracts only the audio track, encoded as AudioCodec at
+	// AudioBitrate kbps.
+	Audio        bool
+	AudioCodec   string
+	AudioBitrate int
+}
+
+// Key identifies the format in cache keys and logs.
+func (f Format) Key() string {
+	if f.Audio {
+		return fmt.Sprintf("audio-%s-%d", f.AudioCodec, f.AudioBitrate)
+	}
+	if f.Height > 0 {
+		return fmt.Sprintf("video-%dp", f.Height)
+	}
+	return "video"
+}
+
+// Request describes a single download job.
+type Request struct {
+	URL     string
+	Service string
+	Format  Format
+	// Dir is the per-job directory the backend writes its files into.
+	Dir string
+
+	// Site options for yt-dlp: a cookie file used if it exists, the format
+	// selector for the default video download and extra options.
+	CookieFile  string
+	VideoFormat string
+	Options     []string
+
+	// MaxFileSize makes the backend give up on files larger than this
+	// many bytes, 0 for no limit.
+	MaxFileSize int64
+
+	// Log, if set, is used instead of the backend's own logger so lines
+	// carry the job's fields.
+	Log Logger
+}
+
+// logger returns r.Log, or fallback if the request has none.
+func (r Request) logger(fallback Logger) Logger {
+	if r.Log != nil {
+		return r.Log
+	}
+	return fallback
+}
+
+// Info is the metadata returned by Probe.
+type Info struct {
+	ID        string
+	Title     string
+	Uploader  string
+	Artist    string
+	Extractor string
+	Duration  time.Duration
+	Formats   []MediaFormat
+
+	// Live is set for a stream that is on air.
+	Live bool
+	// Size is the exact or approximate size of the format the probe
+	// selected, 0 if unknown.
+	Size int64
+}
+
+// Performer returns the artist if known, otherwise the uploader.
+func (i *Info) Performer() string {
+	if i.Artist != "" {
+		return i.Artist
+	}
+	return i.Uploader
+}
+
+// Result lists the media a backend produced inside Request.Dir, e.g. every
+// photo and video of a carousel. Info is set when the backend learned the
+// media metadata while downloading. Conversions holds how long each video
+// converted to MP4 took, for the caller to record.
+type Result struct {
+	Items       []Item
+	Info        *Info
+	Conversions []time.Duration
+}
+
+// Size returns the total size of the items on disk.
+func (r *Result) Size() int64 {
+	var size int64
+	for _, item := range r.Items {
+		if fi, err := os.Stat(item.Path); err == nil {
+			size += fi.Size()
+		}
+	}
+	return size
+}
+
+// Downloader is a backend able to fetch media for one or more services.
+//
+// Download writes its output into req.Dir and may send updates on progress,
+// which can be nil. It must not send on progress after it returns, so the
+// caller is free to close the channel afterwards. A successful Result holds
+// at least one item; a backend with nothing to show returns ErrNoFiles.
+type Downloader interface {
+	Name() string
+	Probe(ctx context.Context, req Request) (*Info, error)
+	Download(ctx context.Context, req Request, progress chan<- Progress) (*Result, error)
+}
+
+// Registry maps service names to an ordered chain of backends.
+type Registry struct {
+	mu       sync.RWMutex
+	backends map[string][]Downloader
+	fallback []Downloader
+}
+
+func NewRegistry() *Registry {
+	return &Registry{backends: make(map[string][]Downloader)}
+}
+
+// Register appends d to the chain tried for service.
+func (r *Registry) Register(service string, d Downloader) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.backends[service] = append(r.backends[service], d)
+}
+
+// SetDefault sets the chain used for services without their own backends.
+func (r *Registry) SetDefault(d ...Downloader) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = d
+}
+
+// Lookup returns the backends for service in the order they should be tried.
+func (r *Registry) Lookup(service string) []Downloader {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if chain, ok := r.backends[service]; ok {
+		return append([]Downloader(nil), chain...)
+	}
+	return append([]Downloader(nil), r.fallback...)
+}
+
+// Probe asks the first backend of the chain for req.Service that supports
+// probing. req.Dir is not used.
+func (r *Registry) Probe(ctx context.Context, req Request) (*Info, error) {
+	chain := r.Lookup(req.Service)
+	if len(chain) == 0 {
+		return nil, ErrNoBackend
+	}
+	for _, d := range chain {
+		info, err := d.Probe(ctx, req)
+		if errors.Is(err, ErrProbeUnsupported) {
+			continue
+		}
+		return info, err
+	}
+	return nil, ErrProbeUnsupported
+}
+
+// Download tries each backend registered for req.Service until one succeeds.
+// A backend that reports success without any items is treated as having
+// failed with ErrNoFiles, so callers can rely on a non-empty Result.
+func (r *Registry) Download(ctx context.Context, req Request, progress chan<- Progress) (*Result, error) {
+	chain := r.Lookup(req.Service)
+	if len(chain) == 0 {
+		return nil, ErrNoBackend
+	}
+
+	var errs []error
+	for _, d := range chain {
+		res, err := d.Download(ctx, req, progress)
+		if err == nil && (res == nil || len(res.Items) == 0) {
+			err = ErrNoFiles
+		}
+		if err == nil {
+			return res, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", d.Name(), err))
+
+		// Leave the next backend an empty directory
+		clearDir(req.Dir)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
@@ -0,0 +1,71 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRegistryFallsBackAfterFailure(t *testing.T) {
+	dir := t.TempDir()
+	broken := &Fake{Err: errors.New("login required")}
+	working := &Fake{Files: map[string][]byte{"a.mp4": []byte("video"), "b.jpg": []byte("photo")}}
+
+	// What the failed backend leaves behind must not reach the next one
+	os.WriteFile(filepath.Join(dir, "partial.mp4.part"), []byte("x"), 0o644)
+
+	r := NewRegistry()
+	r.Register("Instagram", broken)
+	r.Register("Instagram", working)
+
+	res, err := r.Download(context.Background(), Request{URL: "https://instagram.com/p/x", Service: "Instagram", Dir: dir}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Items) != 2 || res.Items[0].Kind != Video || res.Items[1].Kind != Photo {
+		t.Fatalf("got items %+v", res.Items)
+	}
+	if len(broken.Requests()) != 1 || len(working.Requests()) != 1 {
+		t.Errorf("backends called %d and %d times, want once each", len(broken.Requests()), len(working.Requests()))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "partial.mp4.part")); err == nil {
+		t.Error("files of the failed backend were left for the next one")
+	}
+}
+
+func TestRegistryRejectsEmptyResult(t *testing.T) {
+	r := NewRegistry()
+	r.SetDefault(&Fake{})
+
+	res, err := r.Download(context.Background(), Request{Service: "Unknown", Dir: t.TempDir()}, nil)
+	if !errors.Is(err, ErrNoFiles) {
+		t.Fatalf("got %v, %v; want ErrNoFiles", res, err)
+	}
+}
+
+func TestRegistryWithoutBackend(t *testing.T) {
+	r := NewRegistry()
+	if _, err := r.Download(context.Background(), Request{Service: "YouTube"}, nil); !errors.Is(err, ErrNoBackend) {
+		t.Errorf("got %v, want ErrNoBackend", err)
+	}
+}
+
+func TestRegistryStopsWhenCanceled(t *testing.T) {
+	first := &Fake{Steps: []float64{50}, Delay: time.Hour}
+	second := &Fake{Files: map[string][]byte{"a.mp4": nil}}
+	r := NewRegistry()
+	r.SetDefault(first, second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := r.Download(ctx, Request{Dir: t.TempDir()}, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if len(second.Requests()) != 0 {
+		t.Error("the next backend ran after cancellation")
+	}
+}
@@ -0,0 +1,133 @@
+package downloader
+
+import (
+	"errors"
+	"strings"
+)
+
+// Failure is a class of download failure, recognised from what yt-dlp
+// wrote to stderr. A Failure is an error itself, so callers can test for it
+// with errors.Is(err, downloader.Private).
+type Failure string
+
+// Failures the bot has a specific message for.
+const (
+	Private        Failure = "private"
+	LoginRequired  Failure = "login_required"
+	AgeRestricted  Failure = "age_restricted"
+	GeoBlocked     Failure = "geo_blocked"
+	NotFound       Failure = "not_found"
+	UnsupportedURL Failure = "unsupported_url"
+	SiteRateLimit  Failure = "site_rate_limited"
+	TooLarge       Failure = "too_large"
+	Network        Failure = "network"
+)
+
+func (f Failure) Error() string { return "downloader: " + strings.ReplaceAll(string(f), "_", " ") }
+
+// failurePatterns maps lower-cased fragments of yt-dlp errors to the
+// failure they describe. The first match wins, so more specific fragments
+// come first: "Sign in to confirm your age" is an age check, not a login
+// wall, and "Unable to download webpage: HTTP Error 404" a missing page,
+// not a network error.
+var failurePatterns = []struct {
+	failure  Failure
+	patterns []string
+}{
+	{AgeRestricted, []string{"confirm your age", "age-restricted", "age restricted", "inappropriate for some users", "age verification"}},
+	{Private, []string{"private video", "video is private", "this account is private", "is a private", "private account"}},
+	{GeoBlocked, []string{"not available in your country", "available in your country", "geo restrict", "geo-restrict", "geoblock", "in your location"}},
+	{SiteRateLimit, []string{"http error 429", "too many requests", "confirm you're not a bot", "confirm you’re not a bot", "rate limit exceeded"}},
+	{LoginRequired, []string{"login required", "log in to", "please log in", "sign in to", "--cookies", "requires authentication", "login_required"}},
+	{NotFound, []string{"http error 404", "http error 410", "video unavailable", "has been removed", "been deleted", "no longer available", "does not exist", "unavailable video"}},
+	{UnsupportedURL, []string{"unsupported url", "no suitable extractor", "is not a valid url"}},
+	{TooLarge, []string{"larger than max-filesize", "maximum file size exceeded", "http error 413", "file is too big", "request entity too large"}},
+	{Network, []string{"unable to download webpage", "connection reset", "connection refused", "timed out", "name or service not known", "temporary failure in name resolution", "network is unreachable", "no route to host", "ssl:", "unable to connect", "connection aborted", "remote end closed"}},
+}
+
+// Classify returns the failure described by stderr, or "" if it is not one
+// the bot knows.
+func Classify(stderr string) Failure {
+	s := strings.ToLower(stderr)
+	for _, k := range failurePatterns {
+		for _, p := range k.patterns {
+			if strings.Contains(s, p) {
+				return k.failure
+			}
+		}
+	}
+	return ""
+}
+
+// FailureOf returns the first classified failure in err's tree, or "" if
+// there is none.
+func FailureOf(err error) Failure {
+	var f Failure
+	if errors.As(err, &f) {
+		return f
+	}
+	return ""
+}
+
+// Error is a failed run of an external tool. Reason is the tool's own
+// explanation, such as yt-dlp's last ERROR line, and Failure what the bot
+// made of it.
+type Error struct {
+	Tool    string
+	Failure Failure
+	Reason  string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Reason != "" {
+		return e.Reason
+	}
+	return e.Tool + ": " + e.Err.Error()
+}
+
+// Unwrap exposes both the failure and the underlying error, e.g. the exit
+// status, to errors.Is and errors.As.
+func (e *Error) Unwrap() []error {
+	if e.Failure == "" {
+		return []error{e.Err}
+	}
+	return []error{e.Failure, e.Err}
+}
+
+// maxStderrLines is how much of a tool's stderr is kept for classifying
+// a failure.
+const maxStderrLines = 20
+
+// stderrTail keeps the ERROR lines and the last few lines a tool wrote to
+// stderr. yt-dlp runs with --verbose, so the lines worth classifying are
+// buried among debug output.
+type stderrTail struct {
+	errors []string
+	last   []string
+}
+
+func (t *stderrTail) add(line string) {
+	if strings.HasPrefix(line, "ERROR:") && len(t.errors) < maxStderrLines {
+		t.errors = append(t.errors, line)
+	}
+	t.last = append(t.last, line)
+	if len(t.last) > maxStderrLines {
+		t.last = t.last[1:]
+	}
+}
+
+// toolError wraps err, the failure of tool, with what its stderr says.
+func (t *stderrTail) toolError(tool string, err error) *Error {
+	lines := t.errors
+	if len(lines) == 0 {
+		lines = t.last
+	}
+	text := strings.Join(lines, "\n")
+
+	e := &Error{Tool: tool, Failure: Classify(text), Err: err}
+	if len(t.errors) > 0 {
+		e.Reason = strings.TrimSpace(strings.TrimPrefix(t.errors[len(t.errors)-1], "ERROR:"))
+	}
+	return e
+}
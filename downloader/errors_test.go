@@ -0,0 +1,58 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		stderr string
+		want   Failure
+	}{
+		{"ERROR: [youtube] abc: Sign in to confirm your age. This video may be inappropriate for some users.", AgeRestricted},
+		{"ERROR: [youtube] abc: Private video. Sign in if you've been granted access to this video", Private},
+		{"ERROR: [Instagram] xyz: Requested content is not available, rate-limit reached or login required. Use --cookies", LoginRequired},
+		{"ERROR: [youtube] abc: The uploader has not made this video available in your country", GeoBlocked},
+		{"ERROR: [youtube] abc: Sign in to confirm you’re not a bot. Use --cookies-from-browser", SiteRateLimit},
+		{"ERROR: unable to download video data: HTTP Error 429: Too Many Requests", SiteRateLimit},
+		{"ERROR: [youtube] abc: Video unavailable. This video has been removed by the uploader", NotFound},
+		{"ERROR: [generic] Unable to download webpage: HTTP Error 404: Not Found", NotFound},
+		{"ERROR: Unsupported URL: https://example.com/", UnsupportedURL},
+		{"ERROR: File is larger than max-filesize (52428800 bytes > 10485760 bytes). Aborting.", TooLarge},
+		{"curl: (63) Maximum file size exceeded", TooLarge},
+		{"ERROR: [youtube] abc: Unable to download webpage: <urlopen error [Errno -3] Temporary failure in name resolution>", Network},
+		{"ERROR: Connection reset by peer", Network},
+		{"ERROR: something nobody has seen before", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.stderr); got != tt.want {
+			t.Errorf("Classify(%q) = %q, want %q", tt.stderr, got, tt.want)
+		}
+	}
+}
+
+func TestToolErrorThroughRegistry(t *testing.T) {
+	var tail stderrTail
+	tail.add("[debug] Command-line config: ...")
+	tail.add("ERROR: [youtube] abc: Private video. Sign in if you've been granted access to this video")
+	tail.add("[debug] trailing noise")
+	toolErr := tail.toolError("yt-dlp", errors.New("exit status 1"))
+
+	// The registry wraps and joins the errors of every backend it tried
+	err := errors.Join(fmt.Errorf("yt-dlp: %w", toolErr), errors.New("instagram-api: failed"))
+	if got := FailureOf(err); got != Private {
+		t.Errorf("FailureOf = %q, want private", got)
+	}
+	if !errors.Is(err, Private) {
+		t.Error("errors.Is(err, Private) is false")
+	}
+	if toolErr.Error() != "[youtube] abc: Private video. Sign in if you've been granted access to this video" {
+		t.Errorf("Error() = %q", toolErr.Error())
+	}
+	if FailureOf(errors.New("exit status 1")) != "" {
+		t.Error("an unclassified error has a failure")
+	}
+}
@@ -0,0 +1,78 @@
+package downloader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is an in-memory backend for exercising the bot without yt-dlp
+// installed. It writes Files into the job directory and reports Steps as
+// progress before returning Err, if set.
+type Fake struct {
+	Info  Info
+	Files map[string][]byte
+	Steps []float64
+	Delay time.Duration
+	Err   error
+
+	mu       sync.Mutex
+	requests []Request
+}
+
+func (f *Fake) Name() string { return "fake" }
+
+// Requests returns every request passed to Download so far.
+func (f *Fake) Requests() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Request(nil), f.requests...)
+}
+
+func (f *Fake) Probe(ctx context.Context, req Request) (*Info, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	info := f.Info
+	return &info, nil
+}
+
+func (f *Fake) Download(ctx context.Context, req Request, progress chan<- Progress) (*Result, error) {
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	f.mu.Unlock()
+
+	for _, step := range f.Steps {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(f.Delay):
+		}
+		if progress != nil {
+			progress <- Progress{Percent: step}
+		}
+	}
+	if f.Err != nil {
+		return nil, f.Err
+	}
+
+	names := make([]string, 0, len(f.Files))
+	for name := range f.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	info := f.Info
+	res := &Result{Info: &info}
+	for _, name := range names {
+		path := filepath.Join(req.Dir, name)
+		if err := os.WriteFile(path, f.Files[name], 0644); err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, NewItem(path))
+	}
+	return res, nil
+}
@@ -0,0 +1,125 @@
+package downloader
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MediaFormat is one of the encodings a site offers for a URL.
+type MediaFormat struct {
+	ID     string
+	Ext    string
+	Height int
+	Video  bool
+	Audio  bool
+	// Size is the exact or approximate size in bytes, 0 if unknown.
+	Size int64
+	// Bitrate is the total bitrate in kbps, 0 if unknown.
+	Bitrate float64
+}
+
+// Quality is a download choice offered to the user.
+type Quality struct {
+	Label  string
+	Format Format
+	// Size is the estimated download size in bytes, 0 if unknown.
+	Size int64
+}
+
+// Qualities returns a choice for each of heights that info can satisfy,
+// followed by audio if info has an audio track. Sizes are estimated from the
+// formats the site reported.
+func Qualities(info *Info, heights []int, audio Format) []Quality {
+	maxHeight := maxVideoHeight(info)
+
+	sorted := append([]int(nil), heights...)
+	sort.Ints(sorted)
+
+	var qualities []Quality
+	for _, h := range sorted {
+		if h > maxHeight {
+			continue
+		}
+		f := Format{Height: h}
+		qualities = append(qualities, Quality{
+			Label:  fmt.Sprintf("%dp", h),
+			Format: f,
+			Size:   EstimateSize(info, f),
+		})
+	}
+
+	if hasAudio(info) {
+		qualities = append(qualities, Quality{Label: audio.AudioCodec, Format: audio, Size: EstimateSize(info, audio)})
+	}
+	return qualities
+}
+
+// EstimateSize returns the expected download size of info in format f, or
+// 0 if the site did not report enough to tell.
+func EstimateSize(info *Info, f Format) int64 {
+	bestAudio := bestAudioSize(info)
+	switch {
+	case f.Audio:
+		if f.AudioBitrate > 0 && info.Duration > 0 {
+			// The audio is re-encoded, so the target bitrate decides the size
+			return int64(float64(f.AudioBitrate) * 1000 / 8 * info.Duration.Seconds())
+		}
+		return bestAudio
+	case f.Height > 0:
+		return videoSize(info, f.Height, bestAudio)
+	case info.Size > 0:
+		return info.Size
+	}
+	return videoSize(info, maxVideoHeight(info), bestAudio)
+}
+
+func maxVideoHeight(info *Info) int {
+	height := 0
+	for _, f := range info.Formats {
+		if f.Video && f.Height > height {
+			height = f.Height
+		}
+	}
+	return height
+}
+
+// videoSize estimates the size of the best format not taller than height,
+// adding the audio track when the video format has none.
+func videoSize(info *Info, height int, audioSize int64) int64 {
+	var best *MediaFormat
+	for i := range info.Formats {
+		f := &info.Formats[i]
+		if !f.Video || f.Height > height {
+			continue
+		}
+		if best == nil || f.Height > best.Height || (f.Height == best.Height && f.Size > best.Size) {
+			best = f
+		}
+	}
+	if best == nil || best.Size == 0 {
+		return 0
+	}
+	if best.Audio {
+		return best.Size
+	}
+	return best.Size + audioSize
+}
+
+func bestAudioSize(info *Info) int64 {
+	var size int64
+	for _, f := range info.Formats {
+		if f.Audio && !f.Video && f.Size > size {
+			size = f.Size
+		}
+	}
+	return size
+}
+
+func hasAudio(info *Info) bool {
+	for _, f := range info.Formats {
+		if f.Audio {
+			return true
+		}
+	}
+	return false
+}
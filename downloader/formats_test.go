@@ -0,0 +1,66 @@
+package downloader
+
+import (
+	"testing"
+	"time"
+)
+
+// threeHourStream is a long video with separate video and audio formats.
+var threeHourStream = &Info{
+	Title:    "stream",
+	Duration: 3 * time.Hour,
+	Size:     3_000_000_000,
+	Formats: []MediaFormat{
+		{ID: "140", Audio: true, Size: 170_000_000},
+		{ID: "134", Height: 360, Video: true, Size: 40_000_000},
+		{ID: "136", Height: 720, Video: true, Size: 900_000_000},
+		{ID: "18", Height: 360, Video: true, Audio: true, Size: 30_000_000},
+	},
+}
+
+func TestEstimateSize(t *testing.T) {
+	tests := []struct {
+		name   string
+		info   *Info
+		format Format
+		want   int64
+	}{
+		{"default uses the probed size", threeHourStream, Format{}, 3_000_000_000},
+		{"height adds the audio track", threeHourStream, Format{Height: 720}, 900_000_000 + 170_000_000},
+		{"largest format of the height wins", threeHourStream, Format{Height: 480}, 40_000_000 + 170_000_000},
+		{"audio follows the target bitrate", threeHourStream, Format{Audio: true, AudioBitrate: 64}, 64 * 1000 / 8 * 3 * 3600},
+		{"audio without bitrate uses the best track", threeHourStream, Format{Audio: true}, 170_000_000},
+		{"default falls back to the best height", &Info{Formats: []MediaFormat{{Height: 1080, Video: true, Audio: true, Size: 5}}}, Format{}, 5},
+		{"nothing known", &Info{}, Format{}, 0},
+	}
+	for _, tt := range tests {
+		if got := EstimateSize(tt.info, tt.format); got != tt.want {
+			t.Errorf("%s: EstimateSize = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestQualities(t *testing.T) {
+	audio := Format{Audio: true, AudioCodec: MP3, AudioBitrate: 192}
+	got := Qualities(threeHourStream, []int{1080, 360, 720}, audio)
+
+	want := []Quality{
+		{Label: "360p", Format: Format{Height: 360}, Size: 40_000_000 + 170_000_000},
+		{Label: "720p", Format: Format{Height: 720}, Size: 900_000_000 + 170_000_000},
+		{Label: "mp3", Format: audio, Size: 192 * 1000 / 8 * 3 * 3600},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d qualities %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("quality %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// No audio track, no audio choice
+	silent := &Info{Formats: []MediaFormat{{Height: 720, Video: true}}}
+	if q := Qualities(silent, []int{720}, audio); len(q) != 1 || q[0].Format.Audio {
+		t.Errorf("silent video got %+v", q)
+	}
+}
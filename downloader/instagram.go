@@ -0,0 +1,117 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// shortcodeRe finds the shortcode in post and reel links, including the
+// canonical /p/<shortcode>/ form the bot rewrites reels to.
+var shortcodeRe = regexp.MustCompile(`/(?:p|reels?|tv)/([A-Za-z0-9_-]+)`)
+
+// CookieExists checks if the cookie file at path exists.
+func CookieExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// WriteSampleInstagramCookie creates a sample Instagram cookie file with
+// instructions.
+func WriteSampleInstagramCookie(path string) error {
+	content := `# This is a sample Instagram cookie file
+# To use real cookies:
+# 1. Log into Instagram in your browser
+# 2. Use a browser extension to export cookies (like "Get cookies.txt" for Chrome)
+# 3. Replace this file with the exported cookies
+# 4. Make sure the file is named "instagram_cookies.txt" in the same directory as the bot
+
+# Format should be: domain_name	TRUE/FALSE	path	secure	expiry	name	value
+.instagram.com	TRUE	/	TRUE	1708123456	sessionid	your_session_id_here
+.instagram.com	TRUE	/	TRUE	1708123456	ds_user_id	your_user_id_here
+`
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+// InstagramAPI downloads Instagram reels through a third-party API. It is
+// registered after yt-dlp as a fallback for when Instagram asks for a login.
+type InstagramAPI struct {
+	APIKey string
+	Log    Logger
+}
+
+func NewInstagramAPI(apiKey string, log Logger) *InstagramAPI {
+	return &InstagramAPI{APIKey: apiKey, Log: log}
+}
+
+func (a *InstagramAPI) Name() string { return "instagram-api" }
+
+func (a *InstagramAPI) Probe(ctx context.Context, req Request) (*Info, error) {
+	return nil, ErrProbeUnsupported
+}
+
+func (a *InstagramAPI) Download(ctx context.Context, req Request, progress chan<- Progress) (*Result, error) {
+	if req.Format.Audio {
+		return nil, ErrFormatUnsupported
+	}
+	log := req.logger(a.Log)
+
+	log.Infof("Attempting Instagram direct download via API for: %s", req.URL)
+
+	// Extract Instagram ID from URL
+	matches := shortcodeRe.FindStringSubmatch(req.URL)
+	if len(matches) < 2 {
+		return nil, fmt.Errorf("no shortcode in %s: %w", req.URL, UnsupportedURL)
+	}
+
+	instagramID := matches[1]
+	log.Infof("Extracted Instagram ID: %s", instagramID)
+
+	// NOTE: Replace with a real working Instagram API service
+	apiURL := fmt.Sprintf("https://instagram-downloader-download-instagram-videos-stories.p.rapidapi.com/index?url=%s", req.URL)
+	outputFile := filepath.Join(req.Dir, instagramID+".mp4")
+
+	if progress != nil {
+		progress <- Progress{Percent: 0}
+	}
+
+	args := []string{
+		"-X", "GET",
+		"-H", "X-RapidAPI-Key: " + a.APIKey,
+		"-H", "X-RapidAPI-Host: instagram-downloader-download-instagram-videos-stories.p.rapidapi.com",
+		"-o", outputFile,
+	}
+	if req.MaxFileSize > 0 {
+		args = append(args, "--max-filesize", strconv.FormatInt(req.MaxFileSize, 10))
+	}
+	cmd := command(ctx, "curl", append(args, apiURL)...)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		log.Errorf("Instagram API download failed: %v\nOutput: %s", err, string(output))
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if f := Classify(string(output)); f != "" {
+			return nil, &Error{Tool: "curl", Failure: f, Err: err}
+		}
+		return nil, err
+	}
+
+	// Check if file exists and has content
+	fileInfo, err := os.Stat(outputFile)
+	if err != nil || fileInfo.Size() == 0 {
+		log.Errorf("Instagram API returned empty file or error")
+		return nil, fmt.Errorf("empty response: %w", ErrNoFiles)
+	}
+
+	if progress != nil {
+		progress <- Progress{Percent: 100}
+	}
+
+	log.Infof("Instagram API download successful: %s", outputFile)
+	return &Result{Items: []Item{NewItem(outputFile)}}, nil
+}
@@ -0,0 +1,69 @@
+package downloader
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Kind is how a produced file should be sent to Telegram.
+type Kind int
+
+const (
+	Document Kind = iota
+	Video
+	Photo
+	Audio
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Video:
+		return "video"
+	case Photo:
+		return "photo"
+	case Audio:
+		return "audio"
+	}
+	return "document"
+}
+
+var (
+	videoExts = map[string]bool{".mp4": true, ".mov": true, ".avi": true, ".mkv": true, ".webm": true}
+	audioExts = map[string]bool{".mp3": true, ".m4a": true, ".ogg": true, ".wav": true, ".opus": true}
+	photoExts = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".webp": true}
+)
+
+// KindOf classifies path by its extension.
+func KindOf(path string) Kind {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch {
+	case videoExts[ext]:
+		return Video
+	case audioExts[ext]:
+		return Audio
+	case photoExts[ext]:
+		return Photo
+	}
+	return Document
+}
+
+// IsVideo reports whether path has a video extension.
+func IsVideo(path string) bool {
+	return KindOf(path) == Video
+}
+
+// IsAudio reports whether path has an audio extension.
+func IsAudio(path string) bool {
+	return KindOf(path) == Audio
+}
+
+// Item is one file produced by a download.
+type Item struct {
+	Path string
+	Kind Kind
+}
+
+// NewItem classifies path and returns it as an Item.
+func NewItem(path string) Item {
+	return Item{Path: path, Kind: KindOf(path)}
+}
@@ -0,0 +1,35 @@
+package downloader
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// killGrace is how long a cancelled process gets to release its pipes
+// before Wait gives up on it.
+const killGrace = 5 * time.Second
+
+// command is exec.CommandContext for the external tools the downloaders
+// run. The process gets its own process group and cancelling ctx kills the
+// whole group, so ffmpeg children of yt-dlp do not outlive it.
+func command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+	cmd.WaitDelay = killGrace
+	return cmd
+}
+
+// clearDir removes everything inside dir, leaving dir itself in place.
+func clearDir(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		os.RemoveAll(filepath.Join(dir, e.Name()))
+	}
+}
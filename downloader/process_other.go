@@ -0,0 +1,12 @@
+//go:build !unix
+
+package downloader
+
+import "os/exec"
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills cmd. Its children are left to exit on their own.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
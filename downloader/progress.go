@@ -0,0 +1,154 @@
+package downloader
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Stage is what a running download is busy with.
+type Stage int
+
+const (
+	Downloading Stage = iota
+	// Merging joins separately downloaded video and audio streams.
+	Merging
+	// ExtractingAudio encodes the audio track for audio-only downloads.
+	ExtractingAudio
+	// Processing is any other yt-dlp post-processing step.
+	Processing
+	// Converting re-encodes a video to MP4.
+	Converting
+)
+
+func (s Stage) String() string {
+	switch s {
+	case Downloading:
+		return "downloading"
+	case Merging:
+		return "merging"
+	case ExtractingAudio:
+		return "extracting_audio"
+	case Processing:
+		return "processing"
+	case Converting:
+		return "converting"
+	}
+	return "unknown"
+}
+
+// Progress is a progress update reported while a download is running.
+// Fields other than Stage are zero when the backend does not know them.
+type Progress struct {
+	Stage Stage
+
+	// Percent of the current file, 0 to 100.
+	Percent float64
+
+	Downloaded int64   // bytes
+	Total      int64   // bytes, possibly an estimate
+	Speed      float64 // bytes per second
+	ETA        time.Duration
+
+	// Fragment is the 1-based fragment being downloaded out of Fragments,
+	// for HLS and DASH streams.
+	Fragment  int
+	Fragments int
+
+	// Postprocessor names the yt-dlp post-processor while processing.
+	Postprocessor string
+}
+
+// Prefixes yt-dlp puts in front of the JSON progress lines, set through
+// --progress-template.
+const (
+	downloadProgressPrefix    = "[bot-progress] "
+	postprocessProgressPrefix = "[bot-postprocess] "
+)
+
+// progressTemplateArgs make yt-dlp print every progress update as one JSON
+// line that parseProgress understands.
+var progressTemplateArgs = []string{
+	"--newline",
+	"--progress-template", "download:" + downloadProgressPrefix + "%(progress)j",
+	"--progress-template", "postprocess:" + postprocessProgressPrefix + "%(progress)j",
+}
+
+// ytdlpProgress is yt-dlp's progress dictionary for both downloads and
+// post-processing.
+type ytdlpProgress struct {
+	Status             string   `json:"status"`
+	DownloadedBytes    float64  `json:"downloaded_bytes"`
+	TotalBytes         float64  `json:"total_bytes"`
+	TotalBytesEstimate float64  `json:"total_bytes_estimate"`
+	Speed              *float64 `json:"speed"`
+	ETA                *float64 `json:"eta"`
+	FragmentIndex      int      `json:"fragment_index"`
+	FragmentCount      int      `json:"fragment_count"`
+	Postprocessor      string   `json:"postprocessor"`
+}
+
+// parseProgress turns a line printed through progressTemplateArgs into a
+// Progress. It reports false for any other line.
+func parseProgress(line string) (Progress, bool) {
+	line = strings.TrimSpace(line)
+
+	if rest, ok := strings.CutPrefix(line, postprocessProgressPrefix); ok {
+		var p ytdlpProgress
+		if json.Unmarshal([]byte(rest), &p) != nil || p.Status == "finished" {
+			return Progress{}, false
+		}
+		return Progress{Stage: postprocessStage(p.Postprocessor), Postprocessor: p.Postprocessor}, true
+	}
+
+	rest, ok := strings.CutPrefix(line, downloadProgressPrefix)
+	if !ok {
+		return Progress{}, false
+	}
+	var p ytdlpProgress
+	if json.Unmarshal([]byte(rest), &p) != nil {
+		return Progress{}, false
+	}
+
+	progress := Progress{
+		Stage:      Downloading,
+		Downloaded: int64(p.DownloadedBytes),
+		Total:      int64(p.TotalBytes),
+		Fragment:   p.FragmentIndex,
+		Fragments:  p.FragmentCount,
+	}
+	if progress.Total == 0 {
+		progress.Total = int64(p.TotalBytesEstimate)
+	}
+	if p.Speed != nil {
+		progress.Speed = *p.Speed
+	}
+	if p.ETA != nil {
+		progress.ETA = time.Duration(*p.ETA) * time.Second
+	}
+
+	switch {
+	case p.Status == "finished":
+		progress.Percent = 100
+	case progress.Total > 0:
+		progress.Percent = 100 * float64(progress.Downloaded) / float64(progress.Total)
+	case progress.Fragments > 0:
+		progress.Percent = 100 * float64(progress.Fragment) / float64(progress.Fragments)
+	}
+	if progress.Percent > 100 {
+		progress.Percent = 100
+	}
+	return progress, true
+}
+
+func postprocessStage(name string) Stage {
+	switch {
+	case name == "Merger":
+		return Merging
+	case name == "ExtractAudio" || strings.HasSuffix(name, "ExtractAudio"):
+		return ExtractingAudio
+	case strings.HasSuffix(name, "VideoConvertor") || strings.HasSuffix(name, "VideoRemuxer"):
+		return Converting
+	}
+	return Processing
+}
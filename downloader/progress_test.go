@@ -0,0 +1,64 @@
+package downloader
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseProgress(t *testing.T) {
+	tests := []struct {
+		line string
+		want Progress
+		ok   bool
+	}{
+		{
+			`[bot-progress] {"status": "downloading", "downloaded_bytes": 2500000, "total_bytes": 10000000, "speed": 1048576.0, "eta": 7}`,
+			Progress{Stage: Downloading, Percent: 25, Downloaded: 2500000, Total: 10000000, Speed: 1048576, ETA: 7 * time.Second},
+			true,
+		},
+		{
+			`[bot-progress] {"status": "downloading", "downloaded_bytes": 500, "total_bytes_estimate": 1000, "speed": null, "eta": null}`,
+			Progress{Stage: Downloading, Percent: 50, Downloaded: 500, Total: 1000},
+			true,
+		},
+		{
+			`[bot-progress] {"status": "downloading", "downloaded_bytes": 100, "fragment_index": 3, "fragment_count": 12}`,
+			Progress{Stage: Downloading, Percent: 25, Downloaded: 100, Fragment: 3, Fragments: 12},
+			true,
+		},
+		{
+			`[bot-progress] {"status": "finished", "downloaded_bytes": 900, "total_bytes": 1000}`,
+			Progress{Stage: Downloading, Percent: 100, Downloaded: 900, Total: 1000},
+			true,
+		},
+		{
+			`  [bot-postprocess] {"status": "started", "postprocessor": "Merger"}`,
+			Progress{Stage: Merging, Postprocessor: "Merger"},
+			true,
+		},
+		{
+			`[bot-postprocess] {"status": "processing", "postprocessor": "FFmpegExtractAudio"}`,
+			Progress{Stage: ExtractingAudio, Postprocessor: "FFmpegExtractAudio"},
+			true,
+		},
+		{
+			`[bot-postprocess] {"status": "started", "postprocessor": "FFmpegVideoConvertor"}`,
+			Progress{Stage: Converting, Postprocessor: "FFmpegVideoConvertor"},
+			true,
+		},
+		{
+			`[bot-postprocess] {"status": "started", "postprocessor": "FFmpegMetadata"}`,
+			Progress{Stage: Processing, Postprocessor: "FFmpegMetadata"},
+			true,
+		},
+		{`[bot-postprocess] {"status": "finished", "postprocessor": "Merger"}`, Progress{}, false},
+		{`[bot-progress] not json`, Progress{}, false},
+		{`[youtube] abc: Downloading webpage`, Progress{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseProgress(tt.line)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseProgress(%q) = %+v, %v; want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
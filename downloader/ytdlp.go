@@ -0,0 +1,352 @@
+package downloader
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// YtDlp downloads media by running the yt-dlp binary.
+type YtDlp struct {
+	Binary string
+	FFmpeg string
+	Log    Logger
+}
+
+func NewYtDlp(log Logger) *YtDlp {
+	return &YtDlp{
+		Binary: "yt-dlp",
+		FFmpeg: "ffmpeg",
+		Log:    log,
+	}
+}
+
+func (y *YtDlp) Name() string { return "yt-dlp" }
+
+// ytdlpInfo is the part of yt-dlp's info JSON the bot reads.
+type ytdlpInfo struct {
+	ID        string  `json:"id"`
+	Title     string  `json:"title"`
+	Uploader  string  `json:"uploader"`
+	Artist    string  `json:"artist"`
+	Extractor string  `json:"extractor"`
+	Duration  float64 `json:"duration"`
+	IsLive    bool    `json:"is_live"`
+
+	// Size of the selected format
+	Filesize       int64 `json:"filesize"`
+	FilesizeApprox int64 `json:"filesize_approx"`
+
+	Formats []struct {
+		ID             string  `json:"format_id"`
+		Ext            string  `json:"ext"`
+		Height         int     `json:"height"`
+		VCodec         string  `json:"vcodec"`
+		ACodec         string  `json:"acodec"`
+		Filesize       int64   `json:"filesize"`
+		FilesizeApprox int64   `json:"filesize_approx"`
+		TBR            float64 `json:"tbr"`
+	} `json:"formats"`
+}
+
+func (m *ytdlpInfo) info() *Info {
+	info := &Info{
+		ID:        m.ID,
+		Title:     m.Title,
+		Uploader:  m.Uploader,
+		Artist:    m.Artist,
+		Extractor: m.Extractor,
+		Duration:  time.Duration(m.Duration * float64(time.Second)),
+		Live:      m.IsLive,
+		Size:      m.Filesize,
+	}
+	if info.Size == 0 {
+		info.Size = m.FilesizeApprox
+	}
+
+	for _, f := range m.Formats {
+		size := f.Filesize
+		if size == 0 {
+			size = f.FilesizeApprox
+		}
+		if size == 0 && f.TBR > 0 && m.Duration > 0 {
+			size = int64(f.TBR * 1000 / 8 * m.Duration)
+		}
+		info.Formats = append(info.Formats, MediaFormat{
+			ID:      f.ID,
+			Ext:     f.Ext,
+			Height:  f.Height,
+			Video:   f.VCodec != "" && f.VCodec != "none",
+			Audio:   f.ACodec != "" && f.ACodec != "none",
+			Size:    size,
+			Bitrate: f.TBR,
+		})
+	}
+	return info
+}
+
+// Probe reads the metadata of req.URL with the same cookies, options and
+// format selection the download would use, so sizes are those of the
+// format that would be downloaded.
+func (y *YtDlp) Probe(ctx context.Context, req Request) (*Info, error) {
+	args := append(y.baseArgs(req), "--dump-json", "--no-warnings", "--no-playlist", req.URL)
+	cmd := command(ctx, y.Binary, args...)
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var tail stderrTail
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			for _, line := range strings.Split(string(exitErr.Stderr), "\n") {
+				tail.add(line)
+			}
+		}
+		return nil, fmt.Errorf("yt-dlp probe: %w", tail.toolError(y.Name(), err))
+	}
+
+	var meta ytdlpInfo
+	if err := json.Unmarshal(out, &meta); err != nil {
+		return nil, fmt.Errorf("yt-dlp probe: %w", err)
+	}
+	return meta.info(), nil
+}
+
+// baseArgs are the options shared by probes and downloads: network
+// settings, the site's cookies and options, and the format selection.
+func (y *YtDlp) baseArgs(req Request) []string {
+	log := req.logger(y.Log)
+	cmdArgs := []string{
+		"--verbose",              // More verbose output
+		"--force-ipv4",           // Force IPv4 (can help with some network issues)
+		"--socket-timeout", "30", // Longer socket timeout
+		"--retries", "10", // More retries
+		"--fragment-retries", "10", // More fragment retries
+		"--no-check-certificate", // Skip certificate validation
+	}
+
+	if req.CookieFile != "" {
+		if CookieExists(req.CookieFile) {
+			log.Infof("Using cookie file %s for authentication", req.CookieFile)
+			cmdArgs = append(cmdArgs, "--cookies", req.CookieFile)
+		} else {
+			log.Infof("Cookie file %s not found, attempting to download without authentication (may fail)", req.CookieFile)
+		}
+	}
+
+	switch {
+	case req.Format.Audio:
+		// Extract the audio track only and let ffmpeg encode it
+		cmdArgs = append(cmdArgs, "-f", "bestaudio/best", "--extract-audio",
+			"--audio-format", req.Format.AudioCodec,
+			"--audio-quality", fmt.Sprintf("%dK", req.Format.AudioBitrate),
+			"--embed-metadata")
+	case req.Format.Height > 0:
+		// Best MP4 not taller than the chosen height, merged with its audio
+		h := req.Format.Height
+		cmdArgs = append(cmdArgs, "-f", fmt.Sprintf(
+			"bv*[height<=%d][ext=mp4]+ba[ext=m4a]/b[height<=%d][ext=mp4]/bv*[height<=%d]+ba/b[height<=%d]", h, h, h, h))
+		cmdArgs = append(cmdArgs, "--merge-output-format", "mp4")
+	case req.VideoFormat != "":
+		// The site asked for its own format selection
+		cmdArgs = append(cmdArgs, "-f", req.VideoFormat)
+	default:
+		// For other services, use the optimal format
+		cmdArgs = append(cmdArgs, "-f", "mp4/bestvideo[ext=mp4]+bestaudio[ext=m4a]/mp4")
+		cmdArgs = append(cmdArgs, "--merge-output-format", "mp4")
+	}
+
+	return append(cmdArgs, req.Options...)
+}
+
+func (y *YtDlp) args(req Request) []string {
+	cmdArgs := y.baseArgs(req)
+
+	// The number keeps carousel items in post order when collect sorts
+	// them by name, the ID keeps items with the same title apart, and the
+	// info JSON gives us title, performer and duration for the upload
+	outputTemplate := req.Dir + "/%(autonumber)s %(title).80B [%(id)s].%(ext)s"
+	cmdArgs = append(cmdArgs, progressTemplateArgs...)
+
+	// A backstop for media the preflight probe could not size
+	if req.MaxFileSize > 0 {
+		cmdArgs = append(cmdArgs, "--max-filesize", strconv.FormatInt(req.MaxFileSize, 10))
+	}
+	return append(cmdArgs, "--write-info-json", "-o", outputTemplate, req.URL)
+}
+
+func (y *YtDlp) Download(ctx context.Context, req Request, progress chan<- Progress) (*Result, error) {
+	log := req.logger(y.Log)
+	cmd := command(ctx, y.Binary, y.args(req)...)
+	log.Infof("Running command: %s", strings.Join(cmd.Args, " "))
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		log.Errorf("Failed to start yt-dlp command: %v", err)
+		return nil, err
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	// Log stderr for debugging and keep what explains a failure
+	var tail stderrTail
+	go func() {
+		defer wg.Done()
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			log.Debugf("yt-dlp stderr: %s", scanner.Text())
+			tail.add(scanner.Text())
+		}
+	}()
+
+	// Process stdout for progress and logging. yt-dlp reports aborting a
+	// download past --max-filesize here rather than on stderr.
+	var tooLarge string
+	go func() {
+		defer wg.Done()
+		scanner := bufio.NewScanner(stdout)
+		var last Progress
+		var lastSent time.Time
+
+		for scanner.Scan() {
+			line := scanner.Text()
+			p, ok := parseProgress(line)
+			if !ok {
+				log.Debugf("yt-dlp stdout: %s", line)
+				if Classify(line) == TooLarge {
+					tooLarge = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "[download]"))
+				}
+				continue
+			}
+			if progress == nil {
+				continue
+			}
+
+			// yt-dlp reports many times a second; pass on stage changes,
+			// whole percents and otherwise about once a second
+			if p.Stage != last.Stage || int(p.Percent) != int(last.Percent) || time.Since(lastSent) >= time.Second {
+				progress <- p
+				last, lastSent = p, time.Now()
+			}
+		}
+	}()
+
+	wg.Wait()
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			log.Errorf("yt-dlp was stopped: %v", ctx.Err())
+			return nil, ctx.Err()
+		}
+		toolErr := tail.toolError(y.Name(), err)
+		if tooLarge != "" && toolErr.Failure == "" {
+			toolErr.Failure, toolErr.Reason = TooLarge, tooLarge
+		}
+		log.Errorf("yt-dlp command failed (%s): %v", string(toolErr.Failure), toolErr)
+		return nil, toolErr
+	}
+
+	res, err := y.collect(ctx, req, progress)
+	if tooLarge != "" && errors.Is(err, ErrNoFiles) {
+		// yt-dlp skips the file and still exits cleanly
+		log.Errorf("yt-dlp gave up on a file: %s", tooLarge)
+		return nil, &Error{Tool: y.Name(), Failure: TooLarge, Reason: tooLarge, Err: err}
+	}
+	return res, err
+}
+
+// collect lists the files yt-dlp left in the job directory in name order,
+// converting non-MP4 videos so Telegram can play them inline. Audio-only
+// downloads keep just the audio files.
+func (y *YtDlp) collect(ctx context.Context, req Request, progress chan<- Progress) (*Result, error) {
+	allFiles, _ := filepath.Glob(filepath.Join(req.Dir, "*"))
+	sort.Strings(allFiles)
+
+	res := &Result{}
+	for _, file := range allFiles {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if strings.HasSuffix(file, ".info.json") {
+			if res.Info == nil {
+				res.Info = readInfoJSON(file)
+			}
+			os.Remove(file)
+			continue
+		}
+
+		item := NewItem(file)
+		if req.Format.Audio && item.Kind != Audio {
+			continue
+		}
+		if item.Kind == Video && strings.ToLower(filepath.Ext(file)) != ".mp4" {
+			if progress != nil {
+				progress <- Progress{Stage: Converting}
+			}
+			var took time.Duration
+			if item.Path, took = y.convertToMP4(ctx, req.logger(y.Log), file); took > 0 {
+				res.Conversions = append(res.Conversions, took)
+			}
+		}
+		res.Items = append(res.Items, item)
+	}
+	if len(res.Items) == 0 {
+		return nil, ErrNoFiles
+	}
+	return res, nil
+}
+
+// readInfoJSON parses a yt-dlp .info.json file, returning nil if it cannot
+// be read.
+func readInfoJSON(path string) *Info {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var meta ytdlpInfo
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil
+	}
+	return meta.info()
+}
+
+// convertToMP4 re-encodes inputFile with ffmpeg and returns the new path
+// and how long the conversion took, or inputFile itself and 0 if the
+// conversion fails.
+func (y *YtDlp) convertToMP4(ctx context.Context, log Logger, inputFile string) (string, time.Duration) {
+	outputFile := strings.TrimSuffix(inputFile, filepath.Ext(inputFile)) + ".mp4"
+	log.Infof("Converting %s to MP4 format", inputFile)
+
+	start := time.Now()
+	convertCmd := command(ctx, y.FFmpeg, "-i", inputFile, "-c:v", "libx264", "-preset", "fast", "-c:a", "aac", "-b:a", "192k", outputFile)
+	convertOutput, convertErr := convertCmd.CombinedOutput()
+	if convertErr != nil {
+		log.Errorf("Conversion failed: %v\nOutput: %s", convertErr, string(convertOutput))
+		return inputFile, 0
+	}
+
+	took := time.Since(start)
+	log.Infof("Converted to MP4: %s in %s", outputFile, took)
+	os.Remove(inputFile)
+	return outputFile, took
+}
@@ -0,0 +1,86 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testLogger sends backend logs to the test log.
+type testLogger struct{ t *testing.T }
+
+func (l testLogger) Debugf(format string, v ...interface{}) { l.t.Logf(format, v...) }
+func (l testLogger) Infof(format string, v ...interface{})  { l.t.Logf(format, v...) }
+func (l testLogger) Errorf(format string, v ...interface{}) { l.t.Logf(format, v...) }
+
+func TestCollectKeepsPostOrder(t *testing.T) {
+	dir := t.TempDir()
+	y := NewYtDlp(testLogger{t})
+	args := y.args(Request{URL: "https://instagram.com/p/x", Dir: dir})
+	template := args[len(args)-2]
+
+	// A carousel of eleven items with the same title, IDs not in post order
+	ids := []string{"k", "b", "j", "a", "i", "c", "h", "d", "g", "e", "f"}
+	var want []string
+	for i, id := range ids {
+		name := strings.NewReplacer(
+			"%(autonumber)s", fmt.Sprintf("%05d", i+1), // yt-dlp's default padding
+			"%(title).80B", "Post",
+			"%(id)s", id,
+			"%(ext)s", "jpg",
+		).Replace(template)
+		if err := os.WriteFile(name, []byte("photo"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, name)
+	}
+
+	res, err := y.collect(context.Background(), Request{Dir: dir}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, item := range res.Items {
+		if item.Path != want[i] {
+			t.Fatalf("item %d is %s, want %s", i, filepath.Base(item.Path), filepath.Base(want[i]))
+		}
+	}
+}
+
+func TestArgsLimitFileSize(t *testing.T) {
+	y := NewYtDlp(testLogger{t})
+	args := strings.Join(y.args(Request{URL: "https://example.com/v", MaxFileSize: 50 << 20}), " ")
+	if !strings.Contains(args, "--max-filesize 52428800") {
+		t.Errorf("args %q have no --max-filesize", args)
+	}
+	if args := strings.Join(y.args(Request{URL: "https://example.com/v"}), " "); strings.Contains(args, "--max-filesize") {
+		t.Errorf("args %q limit the size without a limit", args)
+	}
+}
+
+// fakeYtDlp returns a yt-dlp stand-in that prints stdout and exits with
+// code.
+func fakeYtDlp(t *testing.T, stdout string, code int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "yt-dlp")
+	script := fmt.Sprintf("#!/bin/sh\ncat <<'EOF'\n%s\nEOF\nexit %d\n", stdout, code)
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDownloadPastMaxFileSizeIsTooLarge(t *testing.T) {
+	const abort = "[download] File is larger than max-filesize (104857600 bytes > 52428800 bytes). Aborting."
+	for _, code := range []int{0, 1} {
+		y := NewYtDlp(testLogger{t})
+		y.Binary = fakeYtDlp(t, "[youtube] abc: Downloading webpage\n"+abort, code)
+
+		_, err := y.Download(context.Background(), Request{URL: "https://example.com/v", Dir: t.TempDir(), MaxFileSize: 50 << 20}, nil)
+		if FailureOf(err) != TooLarge {
+			t.Errorf("exit %d: got %v (%q), want too_large", code, err, FailureOf(err))
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Command checks that running name with args succeeds.
+func Command(name string, args ...string) CheckFunc {
+	return func(ctx context.Context) error {
+		out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+		if err == nil {
+			return nil
+		}
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+}
+
+// Writable checks that a file can be created in dir.
+func Writable(dir string) CheckFunc {
+	return func(ctx context.Context) error {
+		f, err := os.CreateTemp(dir, ".healthcheck-*")
+		if err != nil {
+			return err
+		}
+		name := f.Name()
+		f.Close()
+		return os.Remove(name)
+	}
+}
+
+// FreeSpace checks that the file system holding dir has at least min bytes
+// available.
+func FreeSpace(dir string, min uint64) CheckFunc {
+	return func(ctx context.Context) error {
+		free, err := freeBytes(dir)
+		if err != nil {
+			return err
+		}
+		if free < min {
+			return fmt.Errorf("%d MB free, want at least %d MB", free>>20, min>>20)
+		}
+		return nil
+	}
+}
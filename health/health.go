@@ -0,0 +1,172 @@
+// Package health runs the bot's dependency checks in the background and
+// serves their latest results on /healthz and /readyz.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CheckFunc reports a dependency as healthy by returning nil.
+type CheckFunc func(ctx context.Context) error
+
+// Status is the latest result of one check.
+type Status struct {
+	Name    string    `json:"name"`
+	OK      bool      `json:"ok"`
+	Error   string    `json:"error,omitempty"`
+	Checked time.Time `json:"checked"`
+	Took    string    `json:"took"`
+}
+
+// Report is the body of /readyz.
+type Report struct {
+	Ready  bool     `json:"ready"`
+	Checks []Status `json:"checks"`
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker runs its checks every interval, giving each up to timeout.
+type Checker struct {
+	interval time.Duration
+	timeout  time.Duration
+	started  time.Time
+
+	checks []check
+
+	// OnChange, if set, is called when a check starts or stops passing,
+	// and for a check that fails its first run.
+	OnChange func(s Status)
+
+	mu      sync.Mutex
+	results map[string]Status
+}
+
+func New(interval, timeout time.Duration) *Checker {
+	return &Checker{
+		interval: interval,
+		timeout:  timeout,
+		started:  time.Now(),
+		results:  make(map[string]Status),
+	}
+}
+
+// Add registers a check. Add all checks before calling Start.
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.checks = append(c.checks, check{name: name, fn: fn})
+}
+
+// Start runs every check in the background, right away and then every
+// interval until ctx is done.
+func (c *Checker) Start(ctx context.Context) {
+	go func() {
+		c.RunAll(ctx)
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.RunAll(ctx)
+			}
+		}
+	}()
+}
+
+// RunAll runs every check concurrently and stores the results.
+func (c *Checker) RunAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, ch := range c.checks {
+		wg.Add(1)
+		go func(ch check) {
+			defer wg.Done()
+			c.set(c.run(ctx, ch))
+		}(ch)
+	}
+	wg.Wait()
+}
+
+func (c *Checker) run(ctx context.Context, ch check) Status {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := ch.fn(ctx)
+	s := Status{
+		Name:    ch.name,
+		OK:      err == nil,
+		Checked: start,
+		Took:    time.Since(start).Round(time.Millisecond).String(),
+	}
+	if err != nil {
+		s.Error = err.Error()
+	}
+	return s
+}
+
+func (c *Checker) set(s Status) {
+	c.mu.Lock()
+	old, seen := c.results[s.Name]
+	c.results[s.Name] = s
+	c.mu.Unlock()
+
+	changed := (seen && old.OK != s.OK) || (!seen && !s.OK)
+	if changed && c.OnChange != nil {
+		c.OnChange(s)
+	}
+}
+
+// Report returns the latest results in the order the checks were added.
+// The bot is ready once every check has run and passed.
+func (c *Checker) Report() Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r := Report{Ready: true}
+	for _, ch := range c.checks {
+		s, ok := c.results[ch.name]
+		if !ok {
+			s = Status{Name: ch.name, Error: "not run yet"}
+		}
+		r.Ready = r.Ready && s.OK
+		r.Checks = append(r.Checks, s)
+	}
+	return r
+}
+
+// LiveHandler serves /healthz: the process is up and answering.
+func (c *Checker) LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"status": "ok",
+			"uptime": time.Since(c.started).Round(time.Second).String(),
+		})
+	})
+}
+
+// ReadyHandler serves /readyz: 200 if every check passes, 503 otherwise,
+// with each check's status in the body.
+func (c *Checker) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Report()
+		code := http.StatusOK
+		if !report.Ready {
+			code = http.StatusServiceUnavailable
+		}
+		writeJSON(w, code, report)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
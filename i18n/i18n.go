@@ -0,0 +1,68 @@
+package i18n
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lang is a two-letter language code.
+type Lang string
+
+const (
+	Uzbek   Lang = "uz"
+	Russian Lang = "ru"
+	English Lang = "en"
+)
+
+// Supported lists the languages the catalog has translations for.
+var Supported = []Lang{Uzbek, Russian, English}
+
+// Key identifies a message in the catalog.
+type Key string
+
+// Parse maps a Telegram language code such as "ru" or "en-US" to a
+// supported language.
+func Parse(code string) (Lang, bool) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		code = code[:i]
+	}
+	for _, l := range Supported {
+		if Lang(code) == l {
+			return l, true
+		}
+	}
+	return "", false
+}
+
+// Catalog looks up translated messages, falling back to a default language
+// when a translation is missing.
+type Catalog struct {
+	def      Lang
+	messages map[Lang]map[Key]string
+}
+
+// New returns the built-in catalog with def as the fallback language.
+func New(def Lang) *Catalog {
+	return &Catalog{def: def, messages: messages}
+}
+
+// Default returns the fallback language.
+func (c *Catalog) Default() Lang {
+	return c.def
+}
+
+// T formats the message key in lang with args.
+func (c *Catalog) T(lang Lang, key Key, args ...interface{}) string {
+	msg, ok := c.messages[lang][key]
+	if !ok {
+		msg, ok = c.messages[c.def][key]
+	}
+	if !ok {
+		return string(key)
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
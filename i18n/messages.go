@@ -0,0 +1,421 @@
+package i18n
+
+const (
+	Welcome         Key = "welcome"
+	RateLimited     Key = "rate_limited"
+	InvalidURL      Key = "invalid_url"
+	TooManyURLs     Key = "too_many_urls"
+	BatchSummary    Key = "batch_summary"
+	ServiceDisabled Key = "service_disabled"
+	Checking        Key = "checking"
+	QueuePosition   Key = "queue_position"
+	Busy            Key = "busy"
+	Downloading     Key = "downloading"
+	Progress        Key = "progress"
+	ProgressETA     Key = "progress_eta"
+	StageMerging    Key = "stage_merging"
+	StageAudio      Key = "stage_audio"
+	StageProcessing Key = "stage_processing"
+	StageConverting Key = "stage_converting"
+	InstagramLogin  Key = "instagram_login"
+	DownloadFailed  Key = "download_failed"
+	DownloadTimeout Key = "download_timeout"
+	FailPrivate     Key = "fail_private"
+	FailLogin       Key = "fail_login"
+	FailAge         Key = "fail_age"
+	FailGeo         Key = "fail_geo"
+	FailNotFound    Key = "fail_not_found"
+	FailUnsupported Key = "fail_unsupported"
+	FailRateLimit   Key = "fail_rate_limit"
+	FailTooLarge    Key = "fail_too_large"
+	FailNetwork     Key = "fail_network"
+	FailNoMedia     Key = "fail_no_media"
+	ShutdownAborted Key = "shutdown_aborted"
+	Uploading       Key = "uploading"
+	SendTooLarge    Key = "send_too_large"
+	SendFailed      Key = "send_failed"
+	AudioUsage      Key = "audio_usage"
+	Probing         Key = "probing"
+	ChooseQuality   Key = "choose_quality"
+	AudioButton     Key = "audio_button"
+	QualityChosen   Key = "quality_chosen"
+	PickExpired     Key = "pick_expired"
+	LimitLive       Key = "limit_live"
+	LimitDuration   Key = "limit_duration"
+	LimitSize       Key = "limit_size"
+	LimitSizeOffer  Key = "limit_size_offer"
+	PickNotYours    Key = "pick_not_yours"
+	CancelButton    Key = "cancel_button"
+	Canceled        Key = "canceled"
+	CancelDone      Key = "cancel_done"
+	CancelNothing   Key = "cancel_nothing"
+	CancelTooLate   Key = "cancel_too_late"
+
+	LanguageName    Key = "language_name"
+	LanguageCurrent Key = "language_current"
+	LanguageSet     Key = "language_set"
+	LanguageUnknown Key = "language_unknown"
+
+	AdminOnly         Key = "admin_only"
+	SetCookiesUsage   Key = "setcookies_usage"
+	SetCookiesFailed  Key = "setcookies_failed"
+	SetCookiesDone    Key = "setcookies_done"
+	PurgeFailed       Key = "purge_failed"
+	PurgeDone         Key = "purge_done"
+	StatsFailed       Key = "stats_failed"
+	StatsTitle        Key = "stats_title"
+	StatsDay          Key = "stats_day"
+	StatsWeek         Key = "stats_week"
+	StatsAll          Key = "stats_all"
+	StatsPeriod       Key = "stats_period"
+	StatsFooter       Key = "stats_footer"
+	BanUsage          Key = "ban_usage"
+	BadUserID         Key = "bad_user_id"
+	BadDuration       Key = "bad_duration"
+	BanFailed         Key = "ban_failed"
+	BannedForever     Key = "banned_forever"
+	BannedUntil       Key = "banned_until"
+	UnbanUsage        Key = "unban_usage"
+	UnbanFailed       Key = "unban_failed"
+	Unbanned          Key = "unbanned"
+	UserUsage         Key = "user_usage"
+	UserNotFound      Key = "user_not_found"
+	UserFailed        Key = "user_failed"
+	UserInfo          Key = "user_info"
+	UserBanned        Key = "user_banned"
+	UserNotBanned     Key = "user_not_banned"
+	UserRecent        Key = "user_recent"
+	QueueEmpty        Key = "queue_empty"
+	QueueActiveTitle  Key = "queue_active_title"
+	QueuePendingTitle Key = "queue_pending_title"
+)
+
+var messages = map[Lang]map[Key]string{
+	Uzbek: {
+		Welcome: `🎉 Assalomu alaykum! Media Download botiga xush kelibsiz! 
+
+📱 Men sizga quyidagi xizmatlarni taqdim etaman:
+- YouTube video/audio
+- Instagram post/reels
+- TikTok video
+- Facebook video
+
+🔍 Ishlash tartibi:
+1. Yuklab olmoqchi bo'lgan link/URL ni yuboring
+2. Men sizga faylni yuklab beraman!
+
+🎵 Faqat audio kerakmi? /audio <link> [bitreyt] [mp3|m4a]
+
+⚡️ Tezkor va ishonchli xizmat kafolati bilan!
+
+✖️ Yuklab olishni to'xtatish: /cancel
+
+🌐 Tilni o'zgartirish: /language
+
+🤖 Bot @media_download_any_bot`,
+		RateLimited:     "⚠️ Siz vaqtinchalik bloklandingiz yoki juda ko'p so'rov yubordingiz. Iltimos, keyinroq urinib ko'ring.",
+		InvalidURL:      "❌ Iltimos, to'g'ri URL manzil yuboring! Masalan: https://example.com/video",
+		TooManyURLs:     "⚠️ Bitta xabarda ko'pi bilan %d ta link yuklab olinadi, qolganlari e'tiborsiz qoldirildi.",
+		BatchSummary:    "📋 Jami %d ta link: %d tasi yuborildi, %d tasi muvaffaqiyatsiz.",
+		ServiceDisabled: "⛔️ %s dan yuklab olish hozircha o'chirilgan.",
+		Checking:        "🔄 URL tekshirilmoqda...",
+		QueuePosition:   "🕒 Navbatdasiz: %d-o'rin",
+		Busy:            "⏳ Bot hozir band. Iltimos, birozdan keyin qayta urinib ko'ring.",
+		Downloading:     "🔍 %s dan media yuklab olinmoqda...",
+		Progress:        "⏳ %s dan yuklanmoqda... %d%%",
+		ProgressETA:     "⏱ %s qoldi",
+		StageMerging:    "🔧 Video va audio birlashtirilmoqda...",
+		StageAudio:      "🎵 Audio ajratib olinmoqda...",
+		StageProcessing: "⚙️ Fayl qayta ishlanmoqda...",
+		StageConverting: "🔄 Video MP4 formatiga o'tkazilmoqda...",
+		InstagramLogin:  "❌ Instagram video yuklab olishda xatolik yuz berdi.\n\nInstagram himoya tizimi tufayli, login ma'lumotlar talab qilinadi.\n\nAdministratorga murojaat qiling.",
+		DownloadFailed:  "❌ Xatolik: faylni yuklab bo'lmadi. Keyinroq qayta urinib ko'ring.",
+		FailNoMedia:     "🤷 Bu linkda yuklab olinadigan media topilmadi.",
+		ShutdownAborted: "🔄 Bot qayta ishga tushirilmoqda, yuklab olish to'xtatildi. Iltimos, linkni birozdan keyin qayta yuboring.",
+		DownloadTimeout: "⌛ Yuklab olish juda uzoq davom etdi (%s dan ortiq) va to'xtatildi. Keyinroq qayta urinib ko'ring yoki pastroq sifatni tanlang.",
+		FailPrivate:     "🔒 Bu media yopiq (private). Faqat ochiq postlarni yuklab olish mumkin.",
+		FailLogin:       "🔑 %s bu mediani faqat tizimga kirgan foydalanuvchilarga ko'rsatadi. Administratorga murojaat qiling.",
+		FailAge:         "🔞 Bu media yosh cheklovi bilan himoyalangan va uni yuklab bo'lmaydi.",
+		FailGeo:         "🌍 Bu media bot joylashgan mamlakatda mavjud emas.",
+		FailNotFound:    "🗑 Media topilmadi: u o'chirilgan yoki link noto'g'ri.",
+		FailUnsupported: "🤷 Bu link qo'llab-quvvatlanmaydi. Video yoki post linkini yuboring.",
+		FailRateLimit:   "🚦 %s hozircha so'rovlarni cheklamoqda. Birozdan keyin qayta urinib ko'ring.",
+		FailTooLarge:    "📦 Fayl juda katta. Pastroq sifatni yoki /audio ni sinab ko'ring.",
+		FailNetwork:     "📡 %s bilan bog'lanishda tarmoq xatoligi yuz berdi. Keyinroq qayta urinib ko'ring.",
+		Uploading:       "✅ Fayl muvaffaqiyatli yuklandi! Yuborilmoqda...",
+		SendTooLarge:    "❌ Xatolik: faylni yuborib bo'lmadi. Hajmi juda katta bo'lishi mumkin.",
+		SendFailed:      "❌ Xatolik: faylni yuborib bo'lmadi.",
+		AudioUsage:      "🎵 Audio yuklab olish: /audio <link> [bitreyt] [mp3|m4a]\nMasalan: /audio https://youtu.be/xyz 320 mp3\nBitreyt: 32-320 kbps",
+		Probing:         "🔍 Mavjud formatlar tekshirilmoqda...",
+		ChooseQuality:   "🎬 %s\n\nSifatni tanlang:",
+		AudioButton:     "🎵 Audio",
+		QualityChosen:   "✅ Tanlandi: %s",
+		PickExpired:     "⌛️ Tanlash muddati tugadi. Linkni qayta yuboring.",
+		LimitLive:       "🔴 Jonli efirlarni yuklab bo'lmaydi. Efir tugagandan keyin linkni qayta yuboring.",
+		LimitDuration:   "⏱ Video juda uzun: %s (ruxsat etilgani %s gacha).",
+		LimitSize:       "📦 Fayl juda katta: taxminan %s (ruxsat etilgani %s gacha).",
+		LimitSizeOffer:  "📦 %s\n\nFayl juda katta: taxminan %s (ruxsat etilgani %s gacha). Kichikroq variantni tanlang:",
+		PickNotYours:    "⛔️ Bu tugmalar boshqa foydalanuvchi uchun.",
+		CancelButton:    "✖️ Bekor qilish",
+		Canceled:        "🚫 Yuklab olish bekor qilindi.",
+		CancelDone:      "🚫 Bekor qilingan yuklab olishlar: %d",
+		CancelNothing:   "ℹ️ Bekor qilinadigan yuklab olish yo'q.",
+		CancelTooLate:   "ℹ️ Fayl allaqachon yuborilmoqda yoki yuklab olish tugagan.",
+
+		LanguageName:    "O'zbekcha",
+		LanguageCurrent: "🌐 Joriy til: %s\n\nTilni o'zgartirish: /language uz | ru | en",
+		LanguageSet:     "✅ Til o'zgartirildi: %s",
+		LanguageUnknown: "❌ Bunday til yo'q. Mavjud tillar: uz, ru, en",
+
+		AdminOnly:         "⛔️ Bu buyruq faqat adminlar uchun.",
+		SetCookiesUsage:   "❌ Xato format. /setcookies [cookie_matn] ko'rinishida yuboring.",
+		SetCookiesFailed:  "❌ Cookie faylni saqlashda xatolik yuz berdi.",
+		SetCookiesDone:    "✅ Instagram cookies muvaffaqiyatli yangilandi!",
+		PurgeFailed:       "❌ Keshni tozalashda xatolik yuz berdi.",
+		PurgeDone:         "✅ Keshdan %d ta yozuv o'chirildi.",
+		StatsFailed:       "❌ Statistikani o'qib bo'lmadi.",
+		StatsTitle:        "📊 Statistika\n",
+		StatsDay:          "24 soat",
+		StatsWeek:         "7 kun",
+		StatsAll:          "Jami",
+		StatsPeriod:       "\n%s:\n👤 Foydalanuvchilar: %d (yangi %d, faol %d)\n📥 So'rovlar: %d\n✅ Yuklandi: %d (%.1f MB)\n❌ Xatolar: %d\n",
+		StatsFooter:       "\n🚫 Bloklanganlar: %d\n🕒 Navbat: %d kutmoqda, %d ishlamoqda\n💾 Kesh: %d ta fayl",
+		BanUsage:          "❌ Xato format. /ban <id> [muddat] ko'rinishida yuboring. Masalan: /ban 12345 7d",
+		BadUserID:         "❌ Foydalanuvchi ID raqam bo'lishi kerak.",
+		BadDuration:       "❌ Muddat noto'g'ri. Masalan: 30m, 12h, 7d",
+		BanFailed:         "❌ Foydalanuvchini bloklab bo'lmadi.",
+		BannedForever:     "🚫 %d butunlay bloklandi.",
+		BannedUntil:       "🚫 %d %s gacha bloklandi.",
+		UnbanUsage:        "❌ Xato format. /unban <id> ko'rinishida yuboring.",
+		UnbanFailed:       "❌ Blokni olib tashlab bo'lmadi.",
+		Unbanned:          "✅ %d blokdan chiqarildi.",
+		UserUsage:         "❌ Xato format. /user <id> ko'rinishida yuboring.",
+		UserNotFound:      "🤷 Bunday foydalanuvchi topilmadi.",
+		UserFailed:        "❌ Foydalanuvchi ma'lumotlarini o'qib bo'lmadi.",
+		UserInfo:          "👤 %d @%s %s %s\n📅 Birinchi: %s\n📅 Oxirgi: %s\n📥 So'rovlar: %d\n🌐 Til: %s\n",
+		UserBanned:        "🚫 Bloklangan: %s gacha (%s)\n",
+		UserNotBanned:     "✅ Bloklanmagan\n",
+		UserRecent:        "\nOxirgi so'rovlar:\n",
+		QueueEmpty:        "🕒 Navbat bo'sh.",
+		QueueActiveTitle:  "⚙️ Ishlamoqda: %d\n",
+		QueuePendingTitle: "\n🕒 Kutmoqda: %d\n",
+	},
+	Russian: {
+		Welcome: `🎉 Здравствуйте! Добро пожаловать в Media Download бот!
+
+📱 Я умею скачивать:
+- YouTube видео/аудио
+- Instagram посты/reels
+- TikTok видео
+- Facebook видео
+
+🔍 Как пользоваться:
+1. Отправьте ссылку, которую хотите скачать
+2. Я пришлю вам файл!
+
+🎵 Нужно только аудио? /audio <ссылка> [битрейт] [mp3|m4a]
+
+⚡️ Быстро и надёжно!
+
+✖️ Остановить загрузку: /cancel
+
+🌐 Сменить язык: /language
+
+🤖 Бот @media_download_any_bot`,
+		RateLimited:     "⚠️ Вы временно заблокированы или отправили слишком много запросов. Пожалуйста, попробуйте позже.",
+		InvalidURL:      "❌ Пожалуйста, отправьте правильную ссылку! Например: https://example.com/video",
+		TooManyURLs:     "⚠️ Из одного сообщения скачивается не больше %d ссылок, остальные пропущены.",
+		BatchSummary:    "📋 Всего ссылок: %d. Отправлено: %d, не удалось: %d.",
+		ServiceDisabled: "⛔️ Скачивание с %s сейчас отключено.",
+		Checking:        "🔄 Проверяю ссылку...",
+		QueuePosition:   "🕒 Вы в очереди: %d-й",
+		Busy:            "⏳ Бот сейчас занят. Пожалуйста, попробуйте чуть позже.",
+		Downloading:     "🔍 Скачиваю медиа с %s...",
+		Progress:        "⏳ Загрузка с %s... %d%%",
+		ProgressETA:     "⏱ осталось %s",
+		StageMerging:    "🔧 Объединяю видео и аудио...",
+		StageAudio:      "🎵 Извлекаю аудио...",
+		StageProcessing: "⚙️ Обрабатываю файл...",
+		StageConverting: "🔄 Конвертирую видео в MP4...",
+		InstagramLogin:  "❌ Не удалось скачать видео из Instagram.\n\nИз-за защиты Instagram требуется вход в аккаунт.\n\nОбратитесь к администратору.",
+		DownloadFailed:  "❌ Ошибка: не удалось скачать файл. Попробуйте позже.",
+		FailNoMedia:     "🤷 По этой ссылке не нашлось медиа для скачивания.",
+		ShutdownAborted: "🔄 Бот перезапускается, загрузка остановлена. Пожалуйста, отправьте ссылку ещё раз чуть позже.",
+		DownloadTimeout: "⌛ Загрузка заняла слишком много времени (больше %s) и была остановлена. Попробуйте позже или выберите качество пониже.",
+		FailPrivate:     "🔒 Это закрытое (приватное) медиа. Скачать можно только открытые публикации.",
+		FailLogin:       "🔑 %s показывает это медиа только вошедшим пользователям. Обратитесь к администратору.",
+		FailAge:         "🔞 Это медиа с возрастным ограничением, скачать его нельзя.",
+		FailGeo:         "🌍 Это медиа недоступно в стране, где работает бот.",
+		FailNotFound:    "🗑 Медиа не найдено: оно удалено или ссылка неверна.",
+		FailUnsupported: "🤷 Эта ссылка не поддерживается. Отправьте ссылку на видео или пост.",
+		FailRateLimit:   "🚦 %s сейчас ограничивает запросы. Попробуйте чуть позже.",
+		FailTooLarge:    "📦 Файл слишком большой. Попробуйте качество пониже или /audio.",
+		FailNetwork:     "📡 Сетевая ошибка при обращении к %s. Попробуйте позже.",
+		Uploading:       "✅ Файл успешно скачан! Отправляю...",
+		SendTooLarge:    "❌ Ошибка: не удалось отправить файл. Возможно, он слишком большой.",
+		SendFailed:      "❌ Ошибка: не удалось отправить файл.",
+		AudioUsage:      "🎵 Скачать аудио: /audio <ссылка> [битрейт] [mp3|m4a]\nНапример: /audio https://youtu.be/xyz 320 mp3\nБитрейт: 32-320 kbps",
+		Probing:         "🔍 Проверяю доступные форматы...",
+		ChooseQuality:   "🎬 %s\n\nВыберите качество:",
+		AudioButton:     "🎵 Аудио",
+		QualityChosen:   "✅ Выбрано: %s",
+		PickExpired:     "⌛️ Время выбора истекло. Отправьте ссылку ещё раз.",
+		LimitLive:       "🔴 Прямые трансляции скачать нельзя. Отправьте ссылку ещё раз после окончания эфира.",
+		LimitDuration:   "⏱ Видео слишком длинное: %s (допускается до %s).",
+		LimitSize:       "📦 Файл слишком большой: примерно %s (допускается до %s).",
+		LimitSizeOffer:  "📦 %s\n\nФайл слишком большой: примерно %s (допускается до %s). Выберите вариант поменьше:",
+		PickNotYours:    "⛔️ Эти кнопки для другого пользователя.",
+		CancelButton:    "✖️ Отмена",
+		Canceled:        "🚫 Загрузка отменена.",
+		CancelDone:      "🚫 Отменено загрузок: %d",
+		CancelNothing:   "ℹ️ Нет загрузок для отмены.",
+		CancelTooLate:   "ℹ️ Файл уже отправляется или загрузка завершена.",
+
+		LanguageName:    "Русский",
+		LanguageCurrent: "🌐 Текущий язык: %s\n\nСменить язык: /language uz | ru | en",
+		LanguageSet:     "✅ Язык изменён: %s",
+		LanguageUnknown: "❌ Такого языка нет. Доступные языки: uz, ru, en",
+
+		AdminOnly:         "⛔️ Эта команда только для администраторов.",
+		SetCookiesUsage:   "❌ Неверный формат. Отправьте /setcookies [текст_cookie].",
+		SetCookiesFailed:  "❌ Не удалось сохранить файл cookie.",
+		SetCookiesDone:    "✅ Cookies Instagram успешно обновлены!",
+		PurgeFailed:       "❌ Не удалось очистить кэш.",
+		PurgeDone:         "✅ Удалено записей из кэша: %d.",
+		StatsFailed:       "❌ Не удалось прочитать статистику.",
+		StatsTitle:        "📊 Статистика\n",
+		StatsDay:          "24 часа",
+		StatsWeek:         "7 дней",
+		StatsAll:          "Всего",
+		StatsPeriod:       "\n%s:\n👤 Пользователи: %d (новых %d, активных %d)\n📥 Запросы: %d\n✅ Скачано: %d (%.1f MB)\n❌ Ошибки: %d\n",
+		StatsFooter:       "\n🚫 Заблокировано: %d\n🕒 Очередь: %d ждут, %d в работе\n💾 Кэш: %d файлов",
+		BanUsage:          "❌ Неверный формат. Отправьте /ban <id> [срок]. Например: /ban 12345 7d",
+		BadUserID:         "❌ ID пользователя должен быть числом.",
+		BadDuration:       "❌ Неверный срок. Например: 30m, 12h, 7d",
+		BanFailed:         "❌ Не удалось заблокировать пользователя.",
+		BannedForever:     "🚫 %d заблокирован навсегда.",
+		BannedUntil:       "🚫 %d заблокирован до %s.",
+		UnbanUsage:        "❌ Неверный формат. Отправьте /unban <id>.",
+		UnbanFailed:       "❌ Не удалось снять блокировку.",
+		Unbanned:          "✅ %d разблокирован.",
+		UserUsage:         "❌ Неверный формат. Отправьте /user <id>.",
+		UserNotFound:      "🤷 Пользователь не найден.",
+		UserFailed:        "❌ Не удалось прочитать данные пользователя.",
+		UserInfo:          "👤 %d @%s %s %s\n📅 Первый запрос: %s\n📅 Последний: %s\n📥 Запросы: %d\n🌐 Язык: %s\n",
+		UserBanned:        "🚫 Заблокирован до %s (%s)\n",
+		UserNotBanned:     "✅ Не заблокирован\n",
+		UserRecent:        "\nПоследние запросы:\n",
+		QueueEmpty:        "🕒 Очередь пуста.",
+		QueueActiveTitle:  "⚙️ В работе: %d\n",
+		QueuePendingTitle: "\n🕒 Ждут: %d\n",
+	},
+	English: {
+		Welcome: `🎉 Hello! Welcome to the Media Download bot!
+
+📱 I can download:
+- YouTube video/audio
+- Instagram posts/reels
+- TikTok videos
+- Facebook videos
+
+🔍 How it works:
+1. Send the link you want to download
+2. I'll send you the file!
+
+🎵 Audio only? /audio <link> [bitrate] [mp3|m4a]
+
+⚡️ Fast and reliable!
+
+✖️ Stop a download: /cancel
+
+🌐 Change language: /language
+
+🤖 Bot @media_download_any_bot`,
+		RateLimited:     "⚠️ You are temporarily blocked or sent too many requests. Please try again later.",
+		InvalidURL:      "❌ Please send a valid URL! For example: https://example.com/video",
+		TooManyURLs:     "⚠️ At most %d links per message are downloaded, the rest were skipped.",
+		BatchSummary:    "📋 %d links in total: %d sent, %d failed.",
+		ServiceDisabled: "⛔️ Downloading from %s is currently disabled.",
+		Checking:        "🔄 Checking the URL...",
+		QueuePosition:   "🕒 You are number %d in the queue",
+		Busy:            "⏳ The bot is busy right now. Please try again in a little while.",
+		Downloading:     "🔍 Downloading media from %s...",
+		Progress:        "⏳ Downloading from %s... %d%%",
+		ProgressETA:     "⏱ %s left",
+		StageMerging:    "🔧 Merging video and audio...",
+		StageAudio:      "🎵 Extracting audio...",
+		StageProcessing: "⚙️ Processing the file...",
+		StageConverting: "🔄 Converting the video to MP4...",
+		InstagramLogin:  "❌ Could not download the Instagram video.\n\nInstagram's protection requires a logged-in account.\n\nPlease contact the administrator.",
+		DownloadFailed:  "❌ Error: could not download the file. Please try again later.",
+		FailNoMedia:     "🤷 No downloadable media was found at this link.",
+		ShutdownAborted: "🔄 The bot is restarting and your download was stopped. Please send the link again in a moment.",
+		DownloadTimeout: "⌛ The download took too long (over %s) and was stopped. Try again later or pick a lower quality.",
+		FailPrivate:     "🔒 This media is private. Only public posts can be downloaded.",
+		FailLogin:       "🔑 %s only shows this media to logged-in users. Please contact the administrator.",
+		FailAge:         "🔞 This media is age-restricted and cannot be downloaded.",
+		FailGeo:         "🌍 This media is not available in the country the bot runs in.",
+		FailNotFound:    "🗑 Media not found: it was removed or the link is wrong.",
+		FailUnsupported: "🤷 This link is not supported. Send a link to a video or post.",
+		FailRateLimit:   "🚦 %s is limiting requests right now. Please try again in a while.",
+		FailTooLarge:    "📦 The file is too large. Try a lower quality or /audio.",
+		FailNetwork:     "📡 A network error occurred while reaching %s. Please try again later.",
+		Uploading:       "✅ File downloaded! Sending...",
+		SendTooLarge:    "❌ Error: could not send the file. It may be too large.",
+		SendFailed:      "❌ Error: could not send the file.",
+		AudioUsage:      "🎵 Download audio: /audio <link> [bitrate] [mp3|m4a]\nFor example: /audio https://youtu.be/xyz 320 mp3\nBitrate: 32-320 kbps",
+		Probing:         "🔍 Checking available formats...",
+		ChooseQuality:   "🎬 %s\n\nChoose the quality:",
+		AudioButton:     "🎵 Audio",
+		QualityChosen:   "✅ Selected: %s",
+		PickExpired:     "⌛️ This choice has expired. Please send the link again.",
+		LimitLive:       "🔴 Live streams cannot be downloaded. Send the link again once the stream is over.",
+		LimitDuration:   "⏱ The video is too long: %s (the limit is %s).",
+		LimitSize:       "📦 The file is too large: about %s (the limit is %s).",
+		LimitSizeOffer:  "📦 %s\n\nThe file is too large: about %s (the limit is %s). Pick a smaller version:",
+		PickNotYours:    "⛔️ These buttons belong to another user.",
+		CancelButton:    "✖️ Cancel",
+		Canceled:        "🚫 The download was cancelled.",
+		CancelDone:      "🚫 Downloads cancelled: %d",
+		CancelNothing:   "ℹ️ You have no downloads to cancel.",
+		CancelTooLate:   "ℹ️ The file is already being sent or the download has finished.",
+
+		LanguageName:    "English",
+		LanguageCurrent: "🌐 Current language: %s\n\nChange language: /language uz | ru | en",
+		LanguageSet:     "✅ Language changed: %s",
+		LanguageUnknown: "❌ Unknown language. Available: uz, ru, en",
+
+		AdminOnly:         "⛔️ This command is for admins only.",
+		SetCookiesUsage:   "❌ Wrong format. Send /setcookies [cookie_text].",
+		SetCookiesFailed:  "❌ Could not save the cookie file.",
+		SetCookiesDone:    "✅ Instagram cookies updated!",
+		PurgeFailed:       "❌ Could not purge the cache.",
+		PurgeDone:         "✅ Removed %d cache entries.",
+		StatsFailed:       "❌ Could not read statistics.",
+		StatsTitle:        "📊 Statistics\n",
+		StatsDay:          "24 hours",
+		StatsWeek:         "7 days",
+		StatsAll:          "All time",
+		StatsPeriod:       "\n%s:\n👤 Users: %d (new %d, active %d)\n📥 Requests: %d\n✅ Downloaded: %d (%.1f MB)\n❌ Failures: %d\n",
+		StatsFooter:       "\n🚫 Banned: %d\n🕒 Queue: %d waiting, %d running\n💾 Cache: %d files",
+		BanUsage:          "❌ Wrong format. Send /ban <id> [duration]. For example: /ban 12345 7d",
+		BadUserID:         "❌ The user ID must be a number.",
+		BadDuration:       "❌ Invalid duration. For example: 30m, 12h, 7d",
+		BanFailed:         "❌ Could not ban the user.",
+		BannedForever:     "🚫 %d banned permanently.",
+		BannedUntil:       "🚫 %d banned until %s.",
+		UnbanUsage:        "❌ Wrong format. Send /unban <id>.",
+		UnbanFailed:       "❌ Could not lift the ban.",
+		Unbanned:          "✅ %d unbanned.",
+		UserUsage:         "❌ Wrong format. Send /user <id>.",
+		UserNotFound:      "🤷 No such user.",
+		UserFailed:        "❌ Could not read the user's data.",
+		UserInfo:          "👤 %d @%s %s %s\n📅 First seen: %s\n📅 Last seen: %s\n📥 Requests: %d\n🌐 Language: %s\n",
+		UserBanned:        "🚫 Banned until %s (%s)\n",
+		UserNotBanned:     "✅ Not banned\n",
+		UserRecent:        "\nRecent requests:\n",
+		QueueEmpty:        "🕒 The queue is empty.",
+		QueueActiveTitle:  "⚙️ Running: %d\n",
+		QueuePendingTitle: "\n🕒 Waiting: %d\n",
+	},
+}
@@ -0,0 +1,144 @@
+// Package janitor keeps the downloads directory from filling the disk by
+// removing leftover job directories. Anything else in the directory, such
+// as the legacy requests.csv, is left alone.
+package janitor
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// jobDirRe matches the <userID>_<unix>_<jobID> names the bot gives job
+// directories, and the <userID>_<unix> ones of older versions.
+var jobDirRe = regexp.MustCompile(`^\d+_\d+(_\d+)?$`)
+
+// Janitor periodically removes job directories in Dir older than MaxAge
+// and, if they still take more than Quota bytes, the oldest remaining ones
+// until they fit.
+type Janitor struct {
+	Dir      string
+	MaxAge   time.Duration
+	Quota    int64 // bytes, 0 for no quota
+	Interval time.Duration
+
+	// InUse reports whether an entry belongs to a running job; such
+	// entries are never removed.
+	InUse func(path string) bool
+
+	Log *slog.Logger
+}
+
+// Result is what one sweep reclaimed.
+type Result struct {
+	Removed int
+	Bytes   int64
+	Total   int64 // bytes left in job directories
+}
+
+type entry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// Run sweeps every Interval until ctx is done.
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+	for {
+		j.sweepAndLog()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *Janitor) sweepAndLog() {
+	res, err := j.Sweep()
+	if err != nil {
+		j.Log.Error("Janitor sweep failed", "dir", j.Dir, "error", err)
+		return
+	}
+	if res.Removed > 0 {
+		j.Log.Info("Janitor reclaimed disk space", "dir", j.Dir, "removed", res.Removed, "bytes", res.Bytes, "left", res.Total)
+	}
+}
+
+// Sweep removes expired entries, then the oldest ones while Dir is over
+// quota.
+func (j *Janitor) Sweep() (Result, error) {
+	entries, err := j.entries()
+	if err != nil {
+		return Result{}, err
+	}
+	sort.Slice(entries, func(a, b int) bool { return entries[a].modTime.Before(entries[b].modTime) })
+
+	var res Result
+	for _, e := range entries {
+		res.Total += e.size
+	}
+
+	cutoff := time.Now().Add(-j.MaxAge)
+	for _, e := range entries {
+		if j.InUse != nil && j.InUse(e.path) {
+			continue
+		}
+		expired := j.MaxAge > 0 && e.modTime.Before(cutoff)
+		overQuota := j.Quota > 0 && res.Total > j.Quota
+		if !expired && !overQuota {
+			continue
+		}
+
+		if err := os.RemoveAll(e.path); err != nil {
+			j.Log.Error("Janitor could not remove entry", "path", e.path, "error", err)
+			continue
+		}
+		res.Removed++
+		res.Bytes += e.size
+		res.Total -= e.size
+	}
+	return res, nil
+}
+
+// entries lists the job directories in Dir with their total size and the
+// newest modification time found inside them.
+func (j *Janitor) entries() ([]entry, error) {
+	dirEntries, err := os.ReadDir(j.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []entry
+	for _, d := range dirEntries {
+		if !d.IsDir() || !jobDirRe.MatchString(d.Name()) {
+			continue
+		}
+		e := entry{path: filepath.Join(j.Dir, d.Name())}
+		filepath.WalkDir(e.path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			if !d.IsDir() {
+				e.size += info.Size()
+			}
+			if info.ModTime().After(e.modTime) {
+				e.modTime = info.ModTime()
+			}
+			return nil
+		})
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
@@ -0,0 +1,72 @@
+package janitor
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSweepOnlyRemovesJobDirectories(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-2 * time.Hour)
+
+	write := func(name string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, old, old)
+		os.Chtimes(filepath.Dir(path), old, old)
+		return path
+	}
+	csv := write("requests.csv")
+	job := write("42_1700000000_7/video.mp4")
+	legacy := write("42_1600000000/video.mp4")
+	other := write("backup/video.mp4")
+
+	j := &Janitor{Dir: dir, MaxAge: time.Hour, Log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	res, err := j.Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Removed != 2 {
+		t.Errorf("removed %d entries, want 2", res.Removed)
+	}
+	for _, path := range []string{job, legacy} {
+		if _, err := os.Stat(filepath.Dir(path)); !os.IsNotExist(err) {
+			t.Errorf("expired job directory %s was kept", filepath.Dir(path))
+		}
+	}
+	for _, path := range []string{csv, other} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was removed: %v", path, err)
+		}
+	}
+}
+
+func TestSweepKeepsJobsInUse(t *testing.T) {
+	dir := t.TempDir()
+	job := filepath.Join(dir, "42_1700000000_7")
+	os.Mkdir(job, 0o755)
+	os.WriteFile(filepath.Join(job, "a.mp4"), make([]byte, 100), 0o644)
+
+	j := &Janitor{
+		Dir:   dir,
+		Quota: 10,
+		InUse: func(path string) bool { return path == job },
+		Log:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	res, err := j.Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Removed != 0 || res.Total != 100 {
+		t.Errorf("got %+v, want nothing removed and 100 bytes left", res)
+	}
+}
@@ -0,0 +1,625 @@
+package main
+
+import (
+	"bot/config"
+	"bot/downloader"
+	"bot/i18n"
+	"bot/metrics"
+	"bot/queue"
+	"bot/storage"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/telebot.v3"
+)
+
+// handleDownload validates url and queues a download of it in format,
+// answering from the file cache when possible.
+func handleDownload(c telebot.Context, url string, format downloader.Format) error {
+	user := c.Sender()
+	logInfo("Received URL from User %d (@%s): %s [%s]", user.ID, user.Username, url, format.Key())
+
+	if rejectRateLimited(c) {
+		return nil
+	}
+	return startDownload(c, url, format, nil)
+}
+
+// rejectRateLimited tells a rate limited user to slow down and reports
+// whether they were.
+func rejectRateLimited(c telebot.Context) bool {
+	user := c.Sender()
+	if !isRateLimited(user.ID) {
+		return false
+	}
+	logInfo("User %d (@%s) is rate limited", user.ID, user.Username)
+	metrics.RateLimited.Inc()
+	c.Send(tr(c, i18n.RateLimited))
+	return true
+}
+
+// startDownload validates url and queues its download, reporting the
+// outcome to b. Links in a batch skip the quality picker and an invalid
+// one only counts as failed in the summary.
+func startDownload(c telebot.Context, url string, format downloader.Format, b *batch) error {
+	user := c.Sender()
+
+	// URL haqiqatdan ham to'g'rimi?
+	if !isValidURL(url) {
+		logInfo("User %d (@%s) sent invalid URL: %s", user.ID, user.Username, url)
+		if b != nil {
+			b.finish(false)
+			return nil
+		}
+		return c.Send(tr(c, i18n.InvalidURL))
+	}
+
+	// Short links are followed and tracking parameters dropped so the same
+	// media is always logged and cached under the same URL
+	link, err := canon.Canonicalize(context.Background(), url)
+	if err != nil {
+		logError("Could not resolve %s, using it as is: %v", url, err)
+	}
+	if link.URL != url {
+		logInfo("Canonicalized %s to %s (id %q)", url, link.URL, link.ID)
+	}
+	url = link.URL
+
+	service := link.Name()
+	if !serviceEnabled(service) {
+		logInfo("User %d (@%s) sent a link to disabled service %s: %s", user.ID, user.Username, service, url)
+		b.finish(false)
+		return c.Send(tr(c, i18n.ServiceDisabled, service))
+	}
+	requestID := logRequest(user, url, service)
+	metrics.Requests.WithLabelValues(service).Inc()
+
+	statusMsg, err := c.Bot().Send(c.Chat(), tr(c, i18n.Checking))
+	if err != nil {
+		logError("Failed to send initial status message: %v", err)
+		b.finish(false)
+		return err
+	}
+
+	// A repeat request is answered before anything is probed
+	if serveCached(c, statusMsg, url, format) {
+		b.finish(true)
+		return nil
+	}
+
+	// Check the media against the limits before it takes up a place in
+	// the queue. Links of a batch get no quality keyboard: the summary
+	// counts them as failed here and would miss a later pick.
+	picker := b == nil && format == (downloader.Format{}) && pickerEnabled(service)
+	if info := preflight(c, statusMsg, url, service, format, picker); info != nil {
+		if checkLimits(c, statusMsg, info, url, service, requestID, format, b == nil) {
+			b.finish(false)
+			return nil
+		}
+
+		// Let the user pick a quality before downloading a plain link
+		if picker && offerQualities(c, statusMsg, info, url, service, requestID) {
+			return nil
+		}
+	}
+
+	return enqueueDownload(c, statusMsg, url, service, requestID, format, b)
+}
+
+// enqueueDownload queues the download of url in format, reporting on
+// statusMsg. Callers try serveCached first. The outcome is reported to b.
+// From here on statusMsg is only edited through a statusUpdater so busy
+// chats stay within Telegram's flood limits.
+func enqueueDownload(c telebot.Context, statusMsg *telebot.Message, url, service string, requestID uint64, format downloader.Format, b *batch) error {
+	user := c.Sender()
+
+	status := newStatusUpdater(c.Bot(), statusMsg)
+	job := &queue.Job{
+		RequestID: requestID,
+		UserID:    user.ID,
+		ChatID:    c.Chat().ID,
+		URL:       url,
+		Service:   service,
+	}
+	job.Run = func(ctx context.Context, j *queue.Job) error {
+		err := processJob(ctx, c, status, j, format)
+		b.finish(err == nil)
+		return err
+	}
+	job.OnPosition = func(position int) {
+		status.Update(tr(c, i18n.QueuePosition, position), cancelMarkup(c, job.ID))
+	}
+	job.OnCancel = func() {
+		status.Final(tr(c, i18n.Canceled))
+		// A job that ran already reported from Run
+		if job.Started().IsZero() {
+			b.finish(false)
+		}
+	}
+	job.OnAbort = func() {
+		status.Final(tr(c, i18n.ShutdownAborted))
+		b.finish(false)
+	}
+
+	position, err := jobs.Submit(job)
+	if err != nil {
+		logInfo("Queue is full, rejecting request from User %d (@%s): %v", user.ID, user.Username, err)
+		b.finish(false)
+		status.Final(tr(c, i18n.Busy))
+		return nil
+	}
+
+	logInfo("Queued job %d for User %d at position %d", job.ID, user.ID, position)
+	if job.State() == queue.Queued {
+		job.OnPosition(position)
+	}
+	return nil
+}
+
+// serveCached sends url in format again by the Telegram file ID of an
+// earlier upload, replacing statusMsg. It reports whether it did.
+func serveCached(c telebot.Context, statusMsg *telebot.Message, url string, format downloader.Format) bool {
+	cached, ok := fileCache.Get(cacheKey(url, format.Key()))
+	if !ok {
+		return false
+	}
+
+	logInfo("Serving User %d from file cache: %s", c.Sender().ID, cached.Key)
+	if err := sendCached(c, cached); err != nil {
+		logError("Cached file %s could not be sent, downloading again: %v", cached.FileID, err)
+		return false
+	}
+	c.Bot().Delete(statusMsg)
+	return true
+}
+
+// handleAudio downloads only the audio track:
+// /audio <url> [bitrate] [mp3|m4a]
+func handleAudio(c telebot.Context) error {
+	args := c.Args()
+	if len(args) == 0 || len(args) > 3 {
+		return c.Send(tr(c, i18n.AudioUsage))
+	}
+
+	format := downloader.Format{Audio: true, AudioCodec: cfg.AudioCodec, AudioBitrate: cfg.AudioBitrate}
+	for _, arg := range args[1:] {
+		arg = strings.ToLower(arg)
+		if arg == downloader.MP3 || arg == downloader.M4A {
+			format.AudioCodec = arg
+			continue
+		}
+
+		kbps, err := strconv.Atoi(strings.TrimSuffix(arg, "k"))
+		if err != nil || kbps < config.MinAudioBitrate || kbps > config.MaxAudioBitrate {
+			return c.Send(tr(c, i18n.AudioUsage))
+		}
+		format.AudioBitrate = kbps
+	}
+
+	return handleDownload(c, args[0], format)
+}
+
+// processJob downloads the job's URL and sends the result back to the chat,
+// reporting progress through status. Failures are reported to the user
+// before being returned so the queue can mark the job as failed.
+func processJob(ctx context.Context, c telebot.Context, status *statusUpdater, job *queue.Job, format downloader.Format) error {
+	user := c.Sender()
+	service := job.Service
+	log := jobLogger(job)
+	markup := cancelMarkup(c, job.ID)
+	status.Update(tr(c, i18n.Downloading, service), markup)
+
+	progress := make(chan downloader.Progress)
+	done := make(chan bool)
+
+	go func() {
+		var lastText string
+		for p := range progress {
+			text := progressText(c, service, p)
+			if text != lastText {
+				status.Update(text, markup)
+				log.Debug("Download progress", "stage", p.Stage, "percent", int(p.Percent), "speed", int64(p.Speed))
+				lastText = text
+			}
+		}
+		done <- true
+	}()
+
+	// Create a download directory. The job ID keeps apart the jobs of one
+	// user that start within the same second, such as a batch.
+	downloadID := fmt.Sprintf("%d_%d_%d", user.ID, time.Now().Unix(), job.ID)
+	downloadDir := filepath.Join(cfg.DownloadsDir, downloadID)
+	os.MkdirAll(downloadDir, os.ModePerm)
+	activeDirs.Store(downloadDir, struct{}{})
+
+	// Whatever happens, nothing in the job directory outlives the job
+	defer func() {
+		os.RemoveAll(downloadDir)
+		activeDirs.Delete(downloadDir)
+		log.Debug("Removed job directory", "dir", downloadDir)
+	}()
+
+	// yt-dlp and ffmpeg are killed when the job runs out of time
+	ctx, cancel := context.WithTimeout(ctx, cfg.JobTimeout)
+	defer cancel()
+
+	start := time.Now()
+	log.Info("Starting job", "username", user.Username, "format", format.Key())
+	req := downloadRequest(job.URL, service, format, downloadDir)
+	req.Log = slogLogger{log}
+	result, err := downloaders.Download(ctx, req, progress)
+	close(progress)
+	<-done
+	metrics.DownloadDuration.WithLabelValues(service).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		log.Error("Download failed", "failure", string(downloader.FailureOf(err)), "error", err)
+		recordDownload(job, 0, start, err)
+
+		if errors.Is(ctx.Err(), context.Canceled) {
+			reportStopped(c, status, job)
+			return err
+		}
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			metrics.DownloadFailures.WithLabelValues(service, metrics.ClassTimeout).Inc()
+			status.Final(tr(c, i18n.DownloadTimeout, cfg.JobTimeout))
+			return err
+		}
+
+		metrics.DownloadFailures.WithLabelValues(service, failureClass(err)).Inc()
+		status.Final(failureText(c, service, err))
+		return err
+	}
+
+	if errors.Is(ctx.Err(), context.Canceled) {
+		reportStopped(c, status, job)
+		return ctx.Err()
+	}
+	job.SetState(queue.Uploading)
+	status.Update(tr(c, i18n.Uploading))
+
+	fileSize := result.Size()
+	metrics.FileSize.WithLabelValues(service).Observe(float64(fileSize))
+	for _, took := range result.Conversions {
+		metrics.ConversionDuration.Observe(took.Seconds())
+	}
+	log.Info("Download finished", "files", len(result.Items), "bytes", fileSize, "took", time.Since(start))
+
+	// The media, or the error sendFile and sendAlbums reply with, takes
+	// the status message's place
+	uploadStart := time.Now()
+	defer status.Delete()
+	if len(result.Items) > 1 {
+		err = sendAlbums(c, result.Items)
+		recordDownload(job, fileSize, start, err)
+		countUpload(service, uploadStart, err)
+		if err != nil {
+			return err
+		}
+		log.Info("Sent albums", "items", len(result.Items))
+		return nil
+	}
+
+	kind, fileID, err := sendFile(c, result.Items[0], result.Info)
+	recordDownload(job, fileSize, start, err)
+	countUpload(service, uploadStart, err)
+	if err != nil {
+		return err
+	}
+
+	if fileID != "" {
+		entry := storage.CachedFile{Key: cacheKey(job.URL, format.Key()), FileID: fileID, Kind: kind}
+		if err := fileCache.Put(entry); err != nil {
+			log.Error("Could not cache file ID", "error", err)
+		}
+	}
+
+	log.Info("Sent media", "kind", kind)
+	return nil
+}
+
+// progressText renders p for the status message: the stage once yt-dlp
+// is past downloading, otherwise the percentage with size, fragments,
+// speed and ETA as far as they are known.
+func progressText(c telebot.Context, service string, p downloader.Progress) string {
+	switch p.Stage {
+	case downloader.Merging:
+		return tr(c, i18n.StageMerging)
+	case downloader.ExtractingAudio:
+		return tr(c, i18n.StageAudio)
+	case downloader.Processing:
+		return tr(c, i18n.StageProcessing)
+	case downloader.Converting:
+		return tr(c, i18n.StageConverting)
+	}
+
+	text := tr(c, i18n.Progress, service, int(p.Percent))
+	var details []string
+	switch {
+	case p.Total > 0:
+		details = append(details, fmt.Sprintf("📦 %s / %s", humanBytes(p.Downloaded), humanBytes(p.Total)))
+	case p.Downloaded > 0:
+		details = append(details, "📦 "+humanBytes(p.Downloaded))
+	}
+	if p.Fragments > 0 {
+		details = append(details, fmt.Sprintf("🧩 %d/%d", p.Fragment, p.Fragments))
+	}
+	if p.Speed > 0 {
+		details = append(details, fmt.Sprintf("🚀 %s/s", humanBytes(int64(p.Speed))))
+	}
+	if p.ETA > 0 {
+		details = append(details, tr(c, i18n.ProgressETA, p.ETA.Round(time.Second)))
+	}
+	if len(details) > 0 {
+		text += "\n" + strings.Join(details, " · ")
+	}
+	return text
+}
+
+// humanBytes renders a byte count as "850 KB" or "12.3 MB".
+func humanBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.2f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	default:
+		return fmt.Sprintf("%d KB", n>>10)
+	}
+}
+
+// failureClass labels a failed download in metrics by what went wrong, or
+// as ClassDownload if that is not known.
+func failureClass(err error) string {
+	if f := downloader.FailureOf(err); f != "" {
+		return string(f)
+	}
+	if errors.Is(err, downloader.ErrNoFiles) {
+		return metrics.ClassNoMedia
+	}
+	return metrics.ClassDownload
+}
+
+// failureText explains a failed download to the user as specifically as
+// the yt-dlp error it was classified from allows. The error itself is only
+// logged, never shown.
+func failureText(c telebot.Context, service string, err error) string {
+	switch downloader.FailureOf(err) {
+	case downloader.Private:
+		return tr(c, i18n.FailPrivate)
+	case downloader.LoginRequired:
+		if service == "Instagram" {
+			return tr(c, i18n.InstagramLogin)
+		}
+		return tr(c, i18n.FailLogin, service)
+	case downloader.AgeRestricted:
+		return tr(c, i18n.FailAge)
+	case downloader.GeoBlocked:
+		return tr(c, i18n.FailGeo)
+	case downloader.NotFound:
+		return tr(c, i18n.FailNotFound)
+	case downloader.UnsupportedURL:
+		return tr(c, i18n.FailUnsupported)
+	case downloader.SiteRateLimit:
+		return tr(c, i18n.FailRateLimit, service)
+	case downloader.TooLarge:
+		return tr(c, i18n.FailTooLarge)
+	case downloader.Network:
+		return tr(c, i18n.FailNetwork, service)
+	}
+	if errors.Is(err, downloader.ErrNoFiles) {
+		return tr(c, i18n.FailNoMedia)
+	}
+	return tr(c, i18n.DownloadFailed)
+}
+
+// reportStopped counts a job whose context was cancelled. The queue tells
+// the user about their own cancellations through OnCancel; otherwise the bot
+// is shutting down and the user is told so here.
+func reportStopped(c telebot.Context, status *statusUpdater, job *queue.Job) {
+	if job.WasCanceled() {
+		metrics.DownloadFailures.WithLabelValues(job.Service, metrics.ClassCanceled).Inc()
+		return
+	}
+	metrics.DownloadFailures.WithLabelValues(job.Service, metrics.ClassAborted).Inc()
+	status.Final(tr(c, i18n.ShutdownAborted))
+}
+
+// countUpload records how long sending took and whether the job succeeded.
+func countUpload(service string, start time.Time, err error) {
+	metrics.UploadDuration.WithLabelValues(service).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.DownloadFailures.WithLabelValues(service, metrics.ClassUpload).Inc()
+		return
+	}
+	metrics.DownloadSuccesses.WithLabelValues(service).Inc()
+}
+
+// recordDownload stores the outcome of job in the request store.
+func recordDownload(job *queue.Job, size int64, start time.Time, jobErr error) {
+	d := &storage.Download{
+		RequestID: job.RequestID,
+		UserID:    job.UserID,
+		Service:   job.Service,
+		Size:      size,
+		Duration:  time.Since(start),
+	}
+	if jobErr != nil {
+		d.Error = jobErr.Error()
+	}
+	if err := store.AddDownload(d); err != nil {
+		jobLogger(job).Error("Could not store download outcome", "error", err)
+	}
+}
+
+// sendFile sends item as a video, photo, audio or document, telling the user
+// if Telegram refuses it. info, if known, fills in title, performer and
+// duration. It returns the kind of message sent and the file ID Telegram
+// assigned to the upload.
+func sendFile(c telebot.Context, item downloader.Item, info *downloader.Info) (string, string, error) {
+	if info == nil {
+		info = &downloader.Info{}
+	}
+	filePath := item.Path
+	duration := int(info.Duration.Seconds())
+
+	switch item.Kind {
+	case downloader.Video:
+		video := &telebot.Video{
+			File:      telebot.FromDisk(filePath),
+			Caption:   cfg.Caption,
+			Duration:  duration,
+			Streaming: true,
+		}
+
+		err := c.Send(video)
+		if err == nil {
+			return "video", video.FileID, nil
+		}
+		logError("Failed to send video to User %d: %v", c.Sender().ID, err)
+
+		// File might be too large, try sending as document
+		logInfo("Trying to send as document instead")
+		doc := &telebot.Document{
+			File:    telebot.FromDisk(filePath),
+			Caption: cfg.Caption,
+		}
+
+		if docErr := c.Send(doc); docErr != nil {
+			logError("Failed to send document: %v", docErr)
+			c.Send(tr(c, i18n.SendTooLarge))
+			return "", "", docErr
+		}
+		return "document", doc.FileID, nil
+	case downloader.Photo:
+		photo := &telebot.Photo{
+			File:    telebot.FromDisk(filePath),
+			Caption: cfg.Caption,
+		}
+
+		if err := c.Send(photo); err != nil {
+			logError("Failed to send photo: %v", err)
+			c.Send(tr(c, i18n.SendFailed))
+			return "", "", err
+		}
+		return "photo", photo.FileID, nil
+	case downloader.Audio:
+		audio := &telebot.Audio{
+			File:      telebot.FromDisk(filePath),
+			Caption:   cfg.Caption,
+			Title:     info.Title,
+			Performer: info.Performer(),
+			Duration:  duration,
+			FileName:  filepath.Base(filePath),
+		}
+
+		if err := c.Send(audio); err != nil {
+			logError("Failed to send audio: %v", err)
+			c.Send(tr(c, i18n.SendFailed))
+			return "", "", err
+		}
+		return "audio", audio.FileID, nil
+	default:
+		// Send any other file type as document
+		doc := &telebot.Document{
+			File:    telebot.FromDisk(filePath),
+			Caption: cfg.Caption,
+		}
+
+		if err := c.Send(doc); err != nil {
+			logError("Failed to send document: %v", err)
+			c.Send(tr(c, i18n.SendFailed))
+			return "", "", err
+		}
+		return "document", doc.FileID, nil
+	}
+}
+
+// sendCached resends a file Telegram already has by its file ID.
+func sendCached(c telebot.Context, f *storage.CachedFile) error {
+	file := telebot.File{FileID: f.FileID}
+	switch f.Kind {
+	case "video":
+		return c.Send(&telebot.Video{File: file, Caption: cfg.Caption})
+	case "audio":
+		return c.Send(&telebot.Audio{File: file, Caption: cfg.Caption})
+	case "photo":
+		return c.Send(&telebot.Photo{File: file, Caption: cfg.Caption})
+	default:
+		return c.Send(&telebot.Document{File: file, Caption: cfg.Caption})
+	}
+}
+
+// maxAlbumSize is the most items Telegram accepts in one media group.
+const maxAlbumSize = 10
+
+// sendAlbums sends items as media groups of up to maxAlbumSize. Photos and
+// videos share albums; audio and documents each get their own, since
+// Telegram does not mix them with other kinds. The caption goes on the
+// first item sent.
+func sendAlbums(c telebot.Context, items []downloader.Item) error {
+	var visual, audio, docs telebot.Album
+	for _, item := range items {
+		file := telebot.FromDisk(item.Path)
+		switch item.Kind {
+		case downloader.Video:
+			visual = append(visual, &telebot.Video{File: file, Streaming: true})
+		case downloader.Photo:
+			visual = append(visual, &telebot.Photo{File: file})
+		case downloader.Audio:
+			audio = append(audio, &telebot.Audio{File: file, FileName: filepath.Base(item.Path)})
+		default:
+			docs = append(docs, &telebot.Document{File: file, FileName: filepath.Base(item.Path)})
+		}
+	}
+
+	captioned := false
+	for _, group := range []telebot.Album{visual, audio, docs} {
+		for len(group) > 0 {
+			n := min(len(group), maxAlbumSize)
+			album := group[:n]
+			group = group[n:]
+
+			if !captioned {
+				setCaption(album[0], cfg.Caption)
+				captioned = true
+			}
+
+			if err := sendAlbum(c, album); err != nil {
+				logError("Failed to send album of %d items to User %d: %v", len(album), c.Sender().ID, err)
+				c.Send(tr(c, i18n.SendFailed))
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sendAlbum sends a media group, or a single message when it has only one
+// item since Telegram rejects groups of one.
+func sendAlbum(c telebot.Context, album telebot.Album) error {
+	if len(album) == 1 {
+		return c.Send(album[0])
+	}
+	return c.SendAlbum(album)
+}
+
+func setCaption(media telebot.Inputtable, caption string) {
+	switch m := media.(type) {
+	case *telebot.Video:
+		m.Caption = caption
+	case *telebot.Photo:
+		m.Caption = caption
+	case *telebot.Audio:
+		m.Caption = caption
+	case *telebot.Document:
+		m.Caption = caption
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bot/downloader"
+	"bot/i18n"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDownloadFlow(t *testing.T) {
+	tg, bot := setupBot(t)
+	fake := &downloader.Fake{Files: map[string][]byte{"clip.mp4": []byte("video")}, Steps: []float64{50, 100}}
+	downloaders = downloader.NewRegistry()
+	downloaders.SetDefault(fake)
+	startQueue(t, 1)
+
+	const url = "https://example.com/clip"
+	if err := handleMessage(userMessage(bot, 42, url)); err != nil {
+		t.Fatal(err)
+	}
+	drain()
+
+	reqs := fake.Requests()
+	if len(reqs) != 1 || reqs[0].URL != url || reqs[0].Service != "Unknown" || reqs[0].MaxFileSize != cfg.MaxFileSizeMB<<20 {
+		t.Fatalf("backend got %+v", reqs)
+	}
+	video, ok := tg.last("sendVideo")
+	if !ok || video.Params["video"] != "video" || video.Params["caption"] != cfg.Caption {
+		t.Fatalf("sent %+v, calls %v", video, tg.methods())
+	}
+	if _, ok := tg.last("deleteMessage"); !ok {
+		t.Errorf("status message was not removed, calls %v", tg.methods())
+	}
+	if left, _ := os.ReadDir(cfg.DownloadsDir); len(left) != 0 {
+		t.Errorf("job directory left behind: %v", left)
+	}
+
+	// The upload's file ID answers the next request for the same link
+	cached, ok := fileCache.Get(cacheKey(url, downloader.Format{}.Key()))
+	if !ok || cached.Kind != "video" {
+		t.Fatalf("cache has %+v", cached)
+	}
+	if err := handleMessage(userMessage(bot, 42, url)); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(fake.Requests()); n != 1 {
+		t.Errorf("cached link downloaded again, %d downloads", n)
+	}
+	if resent, _ := tg.last("sendVideo"); resent.Params["video"] != cached.FileID {
+		t.Errorf("resent %+v, want file ID %s", resent, cached.FileID)
+	}
+}
+
+func TestDownloadFlowFailure(t *testing.T) {
+	tg, bot := setupBot(t)
+	downloaders = downloader.NewRegistry()
+	downloaders.SetDefault(&downloader.Fake{Err: fmt.Errorf("yt-dlp: %w", downloader.Private)})
+	startQueue(t, 1)
+
+	if err := handleMessage(userMessage(bot, 42, "https://example.com/private")); err != nil {
+		t.Fatal(err)
+	}
+	drain()
+
+	edit, _ := tg.last("editMessageText")
+	if want := catalog.T(catalog.Default(), i18n.FailPrivate); edit.Params["text"] != want {
+		t.Errorf("status ended with %q, want %q", edit.Params["text"], want)
+	}
+	if _, ok := tg.last("sendVideo"); ok {
+		t.Error("a failed download sent a video")
+	}
+}
+
+func TestBatchJobsOfOneUserGetTheirOwnDirectory(t *testing.T) {
+	tg, bot := setupBot(t)
+	fake := &downloader.Fake{
+		Files: map[string][]byte{"clip.mp4": []byte("video")},
+		Steps: []float64{50},
+		Delay: 50 * time.Millisecond,
+	}
+	downloaders = downloader.NewRegistry()
+	downloaders.SetDefault(fake)
+	startQueue(t, 2)
+
+	if err := handleMessage(userMessage(bot, 42, "https://example.com/a https://example.com/b")); err != nil {
+		t.Fatal(err)
+	}
+	drain()
+
+	reqs := fake.Requests()
+	if len(reqs) != 2 || reqs[0].Dir == reqs[1].Dir {
+		t.Fatalf("jobs ran in %+v, want two directories", reqs)
+	}
+	videos := 0
+	for _, m := range tg.methods() {
+		if m == "sendVideo" {
+			videos++
+		}
+	}
+	if videos != 2 {
+		t.Errorf("sent %d videos, want 2; calls %v", videos, tg.methods())
+	}
+	summary, _ := tg.last("sendMessage")
+	if want := catalog.T(catalog.Default(), i18n.BatchSummary, 2, 2, 0); summary.Params["text"] != want {
+		t.Errorf("summary %q, want %q", summary.Params["text"], want)
+	}
+}
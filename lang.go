@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bot/i18n"
+	"bot/storage"
+
+	"gopkg.in/telebot.v3"
+)
+
+// userLang picks the sender's language: the one chosen with /language, then
+// the Telegram client language, then the configured default.
+func userLang(c telebot.Context) i18n.Lang {
+	user := c.Sender()
+	if user == nil {
+		return catalog.Default()
+	}
+
+	if u, err := store.User(user.ID); err == nil && u.Language != "" {
+		if lang, ok := i18n.Parse(u.Language); ok {
+			return lang
+		}
+	}
+	if lang, ok := i18n.Parse(user.LanguageCode); ok {
+		return lang
+	}
+	return catalog.Default()
+}
+
+// tr translates key into the sender's language.
+func tr(c telebot.Context, key i18n.Key, args ...interface{}) string {
+	return catalog.T(userLang(c), key, args...)
+}
+
+// handleLanguage shows the current language or switches to the one given
+// as argument, e.g. "/language ru".
+func handleLanguage(c telebot.Context) error {
+	user := c.Sender()
+	args := c.Args()
+	if len(args) == 0 {
+		lang := userLang(c)
+		return c.Send(catalog.T(lang, i18n.LanguageCurrent, catalog.T(lang, i18n.LanguageName)))
+	}
+
+	lang, ok := i18n.Parse(args[0])
+	if !ok {
+		return c.Send(tr(c, i18n.LanguageUnknown))
+	}
+
+	u := storage.User{ID: user.ID, Username: user.Username, FirstName: user.FirstName, LastName: user.LastName}
+	if err := store.SetLanguage(u, string(lang)); err != nil {
+		logError("Failed to store language for User %d: %v", user.ID, err)
+	}
+
+	logInfo("User %d (@%s) switched language to %s", user.ID, user.Username, lang)
+	return c.Send(catalog.T(lang, i18n.LanguageSet, catalog.T(lang, i18n.LanguageName)))
+}
@@ -0,0 +1,93 @@
+// Package logging builds the bot's slog logger, writing to stdout and to a
+// log file that is rotated by size and age.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Output formats.
+const (
+	Text = "text"
+	JSON = "json"
+)
+
+// Options configures New.
+type Options struct {
+	Level  string // debug, info, warn or error
+	Format string // Text or JSON
+
+	// File is the log file; empty means stdout only.
+	File string
+
+	// The file is rotated once it reaches MaxSizeMB. Rotated files older
+	// than MaxAge or beyond the newest MaxBackups are removed; zero keeps
+	// them.
+	MaxSizeMB  int
+	MaxAge     time.Duration
+	MaxBackups int
+}
+
+// ParseLevel parses a level name as used in Options.Level.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(strings.TrimSpace(s)))
+	return level, err
+}
+
+// New returns a logger for opts and the log file to close on exit.
+func New(opts Options) (*slog.Logger, io.Closer, error) {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, nil, fmt.Errorf("logging: %w", err)
+	}
+
+	var out io.Writer = os.Stdout
+	var file io.Closer = nopCloser{}
+	if opts.File != "" {
+		if err := os.MkdirAll(filepath.Dir(opts.File), os.ModePerm); err != nil {
+			return nil, nil, fmt.Errorf("logging: %w", err)
+		}
+		rotator := &lumberjack.Logger{
+			Filename:   opts.File,
+			MaxSize:    opts.MaxSizeMB,
+			MaxAge:     days(opts.MaxAge),
+			MaxBackups: opts.MaxBackups,
+			LocalTime:  true,
+		}
+		out = io.MultiWriter(os.Stdout, rotator)
+		file = rotator
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch opts.Format {
+	case JSON:
+		handler = slog.NewJSONHandler(out, handlerOpts)
+	case Text, "":
+		handler = slog.NewTextHandler(out, handlerOpts)
+	default:
+		return nil, nil, fmt.Errorf("logging: unknown format %q", opts.Format)
+	}
+	return slog.New(handler), file, nil
+}
+
+// days rounds d up to whole days, the unit lumberjack keeps files for.
+func days(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int((d + 24*time.Hour - 1) / (24 * time.Hour))
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
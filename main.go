@@ -2,87 +2,121 @@ package main
 
 import (
 	"bot/config"
-	"bufio"
-	"encoding/csv"
+	"bot/downloader"
+	"bot/i18n"
+	"bot/janitor"
+	"bot/logging"
+	"bot/queue"
+	"bot/services"
+	"bot/storage"
+	"context"
+	"flag"
 	"fmt"
-	"io/ioutil"
-	"log"
+	"io"
+	"log/slog"
+	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
-	"path/filepath"
-	"regexp"
-	"strconv"
+	"os/signal"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"gopkg.in/telebot.v3"
 )
 
 var (
-	requestLimit    = 3
-	banLimit        = 5
-	rateLimitWindow = 5 * time.Second
-	banDuration     = 30 * time.Second
+	// Bot settings, loaded once at startup
+	cfg *config.Config
 
 	userRequests = make(map[int64][]time.Time)
 	bannedUsers  = make(map[int64]time.Time)
 	mutex        = sync.Mutex{}
 	
-	// Create a logger
-	logger *log.Logger
-	
-	// Cookie file path
-	instagramCookieFile = "instagram_cookies.txt"
+	// Structured logger and the log file behind it
+	logger  *slog.Logger
+	logFile io.Closer
+
+	// Users, requests, download outcomes and bans
+	store *storage.Store
+
+	// Telegram file IDs of already uploaded media
+	fileCache *storage.FileCache
+
+	// Translations of every user-facing message
+	catalog *i18n.Catalog
+
+	// Supported sites, matched by host name and path, and the
+	// canonicalizer every link goes through before it is dispatched
+	sites *services.Registry
+	canon *services.Canonicalizer
+
+	// Download backends, the queue running them and the slots probes
+	// take turns on
+	downloaders *downloader.Registry
+	jobs        *queue.Queue
+	probeSlots  chan struct{}
+
+	// Job directories of running jobs, kept away from the janitor
+	activeDirs sync.Map
+
+	// Pacing of status message edits per chat, and the updaters still
+	// delivering their final state
+	statusEdits   *editLimiter
+	statusUpdates sync.WaitGroup
 )
 
+// initLogger starts with a text logger on stdout so config errors can be
+// reported, and setupLogger replaces it once the config is loaded.
 func initLogger() {
-	// Create logs directory if it doesn't exist
-	os.MkdirAll("logs", os.ModePerm)
-	
-	// Open log file with append mode
-	logFile, err := os.OpenFile("logs/bot.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+}
+
+// setupLogger switches to the configured level, format and rotated log
+// file.
+func setupLogger() error {
+	l, file, err := logging.New(logging.Options{
+		Level:      cfg.LogLevel,
+		Format:     cfg.LogFormat,
+		File:       cfg.LogFile,
+		MaxSizeMB:  cfg.LogMaxSizeMB,
+		MaxAge:     cfg.LogMaxAge,
+		MaxBackups: cfg.LogMaxBackups,
+	})
 	if err != nil {
-		log.Fatalf("Failed to open log file: %v", err)
+		return err
+	}
+	logger, logFile = l, file
+	slog.SetDefault(logger)
+	return nil
+}
+
+// closeLogger flushes and closes the log file.
+func closeLogger() {
+	if logFile != nil {
+		logFile.Close()
 	}
-	
-	// Create logger that writes to both stdout and file
-	logger = log.New(logFile, "", log.LstdFlags)
 }
 
 func logInfo(format string, v ...interface{}) {
-	logMessage := fmt.Sprintf(format, v...)
-	logger.Println("INFO:", logMessage)
-	fmt.Println("INFO:", logMessage)
+	logger.Info(fmt.Sprintf(format, v...))
 }
 
 func logError(format string, v ...interface{}) {
-	logMessage := fmt.Sprintf(format, v...)
-	logger.Println("ERROR:", logMessage)
-	fmt.Println("ERROR:", logMessage)
+	logger.Error(fmt.Sprintf(format, v...))
 }
 
-// Checks if Instagram cookie file exists
-func instagramCookieExists() bool {
-	_, err := os.Stat(instagramCookieFile)
-	return err == nil
+// jobLogger returns a logger that tags every line with the job's fields.
+func jobLogger(job *queue.Job) *slog.Logger {
+	return logger.With("job", job.ID, "user", job.UserID, "service", job.Service, "url", job.URL)
 }
 
-// Creates a sample Instagram cookie file with instructions
-func createSampleInstagramCookie() error {
-	content := `# This is a sample Instagram cookie file
-# To use real cookies:
-# 1. Log into Instagram in your browser
-# 2. Use a browser extension to export cookies (like "Get cookies.txt" for Chrome)
-# 3. Replace this file with the exported cookies
-# 4. Make sure the file is named "instagram_cookies.txt" in the same directory as the bot
-
-# Format should be: domain_name	TRUE/FALSE	path	secure	expiry	name	value
-.instagram.com	TRUE	/	TRUE	1708123456	sessionid	your_session_id_here
-.instagram.com	TRUE	/	TRUE	1708123456	ds_user_id	your_user_id_here
-`
-	return ioutil.WriteFile(instagramCookieFile, []byte(content), 0644)
+// cacheKey identifies an uploaded file by the canonical media URL and the
+// format it was downloaded in.
+func cacheKey(url string, format string) string {
+	return format + "|" + url
 }
 
 func isValidURL(input string) bool {
@@ -105,6 +139,10 @@ func isRateLimited(userID int64) bool {
 
 	now := time.Now()
 
+	if ban, err := store.Ban(userID); err == nil && ban.Active(now) {
+		return true
+	}
+
 	if banEndTime, banned := bannedUsers[userID]; banned {
 		if now.Before(banEndTime) {
 			return true
@@ -116,18 +154,21 @@ func isRateLimited(userID int64) bool {
 	filteredRequests := []time.Time{}
 
 	for _, reqTime := range requests {
-		if now.Sub(reqTime) < rateLimitWindow {
+		if now.Sub(reqTime) < cfg.RateLimitWindow {
 			filteredRequests = append(filteredRequests, reqTime)
 		}
 	}
 
-	if len(filteredRequests) >= banLimit {
-		bannedUsers[userID] = now.Add(banDuration)
+	if len(filteredRequests) >= cfg.BanLimit {
+		bannedUsers[userID] = now.Add(cfg.BanDuration)
 		delete(userRequests, userID)
+		if err := store.SetBan(storage.Ban{UserID: userID, Until: now.Add(cfg.BanDuration), Reason: "rate limit"}); err != nil {
+			logError("Could not store ban for User %d: %v", userID, err)
+		}
 		return true
 	}
 
-	if len(filteredRequests) >= requestLimit {
+	if len(filteredRequests) >= cfg.RequestLimit {
 		return true
 	}
 
@@ -136,243 +177,164 @@ func isRateLimited(userID int64) bool {
 	return false
 }
 
-func logRequest(user *telebot.User, url string) {
-	logFile := "downloads/requests.csv"
-	os.MkdirAll("downloads", os.ModePerm)
-
-	file, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		logError("Could not open log file: %v", err)
-		return
+// logRequest records the request and its user in the store and returns the
+// request ID, or 0 if it could not be stored.
+func logRequest(user *telebot.User, url string, service string) uint64 {
+	u := storage.User{
+		ID:        user.ID,
+		Username:  user.Username,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
 	}
-	defer file.Close()
+	req := &storage.Request{URL: url, Service: service}
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	if err := store.AddRequest(u, req); err != nil {
+		logError("Could not store request: %v", err)
+		return 0
+	}
 
-	loc, _ := time.LoadLocation("Asia/Tashkent")
-	currentTime := time.Now().In(loc).Format("2006-01-02 15:04:05")
+	logInfo("User %d (@%s) requested: %s", user.ID, user.Username, url)
+	return req.ID
+}
 
-	record := []string{
-		fmt.Sprintf("%d", user.ID),
-		user.Username,
-		user.FirstName,
-		user.LastName,
-		url,
-		currentTime,
+// importRequestsCSV loads the old downloads/requests.csv logs into the store.
+func importRequestsCSV(paths []string) error {
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		n, skipped, err := storage.ImportCSV(store, file, cfg.Location, getServiceType)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		logInfo("Imported %d requests from %s, skipped %d imported before", n, path, skipped)
 	}
+	return nil
+}
 
-	if err := writer.Write(record); err != nil {
-		logError("Could not write to log file: %v", err)
+// getServiceType returns the name of the service urlStr belongs to, or
+// services.Unknown.
+func getServiceType(urlStr string) string {
+	if s := sites.Match(urlStr); s != nil {
+		return s.Name
 	}
-	
-	logInfo("User %d (@%s) requested: %s", user.ID, user.Username, url)
+	return services.Unknown
 }
 
-func getServiceType(urlStr string) string {
-	if strings.Contains(urlStr, "youtube.com") || strings.Contains(urlStr, "youtu.be") {
-		return "YouTube"
-	} else if strings.Contains(urlStr, "instagram.com") {
-		return "Instagram"
-	} else if strings.Contains(urlStr, "tiktok.com") {
-		return "TikTok"
-	} else if strings.Contains(urlStr, "facebook.com") || strings.Contains(urlStr, "fb.com") {
-		return "Facebook"
-	}
-	return "Unknown"
+// serviceEnabled reports whether links of the named service are accepted.
+// Unknown links are left to yt-dlp.
+func serviceEnabled(name string) bool {
+	s := sites.Get(name)
+	return s == nil || s.IsEnabled()
 }
 
-func downloadMedia(userID int64, username string, url string, progress chan int) (string, error) {
-	service := getServiceType(url)
-	logInfo("Starting download for User %d (@%s): %s [%s]", userID, username, url, service)
-	
-	// Create a unique download directory for each request
-	downloadID := fmt.Sprintf("%d_%d", userID, time.Now().Unix())
-	downloadDir := fmt.Sprintf("downloads/%s", downloadID)
-	os.MkdirAll(downloadDir, os.ModePerm)
-	
-	outputTemplate := downloadDir + "/%(title)s.%(ext)s"
-	
-	// Basic command arguments
-	cmdArgs := []string{
-		"--verbose",            // More verbose output
-		"--force-ipv4",         // Force IPv4 (can help with some network issues)
-		"--socket-timeout", "30",  // Longer socket timeout
-		"--retries", "10",      // More retries
-		"--fragment-retries", "10", // More fragment retries
-		"--no-check-certificate", // Skip certificate validation
-	}
-	
-	// Special handling for Instagram
-	if service == "Instagram" {
-		if instagramCookieExists() {
-			logInfo("Using Instagram cookie file for authentication")
-			cmdArgs = append(cmdArgs, "--cookies", instagramCookieFile)
-		} else {
-			logInfo("Instagram cookie file not found, creating a sample file")
-			createSampleInstagramCookie()
-			logInfo("Attempting to download without authentication (may fail)")
-		}
-		
-		// For Instagram, use a different format selection
-		cmdArgs = append(cmdArgs, "-f", "best")
-	} else {
-		// For other services, use the optimal format
-		cmdArgs = append(cmdArgs, "-f", "mp4/bestvideo[ext=mp4]+bestaudio[ext=m4a]/mp4")
-		cmdArgs = append(cmdArgs, "--merge-output-format", "mp4")
+// downloadRequest builds the downloader request for url, filling in the
+// service's yt-dlp options and the file size limit.
+func downloadRequest(url, service string, format downloader.Format, dir string) downloader.Request {
+	req := downloader.Request{URL: url, Service: service, Format: format, Dir: dir, MaxFileSize: cfg.MaxFileSizeMB << 20}
+	if s := sites.Get(service); s != nil {
+		req.CookieFile = s.CookieFile
+		req.VideoFormat = s.Format
+		req.Options = s.Args
 	}
-	
-	// Add output template and URL
-	cmdArgs = append(cmdArgs, "-o", outputTemplate, "--newline", url)
-	
-	// Create the command
-	cmd := exec.Command("yt-dlp", cmdArgs...)
+	return req
+}
 
-	// Log the exact command
-	logInfo("Running command: %s", strings.Join(cmd.Args, " "))
-	
-	stderr, _ := cmd.StderrPipe()
-	stdout, _ := cmd.StdoutPipe()
-	
-	err := cmd.Start()
-	if err != nil {
-		logError("Failed to start yt-dlp command: %v", err)
-		return "", err
-	}
+// slogLogger adapts a slog.Logger to downloader.Logger.
+type slogLogger struct{ l *slog.Logger }
 
-	// Log stderr for debugging
-	go func() {
-		scanner := bufio.NewScanner(stderr)
-		for scanner.Scan() {
-			line := scanner.Text()
-			logInfo("yt-dlp stderr: %s", line)
-		}
-	}()
-	
-	// Process stdout for progress and logging
-	go func() {
-		scanner := bufio.NewScanner(stdout)
-		re := regexp.MustCompile(`(\d+\.\d+)%`)
-		lastProgress := 0
-
-		for scanner.Scan() {
-			line := scanner.Text()
-			logInfo("yt-dlp stdout: %s", line)
-			
-			match := re.FindStringSubmatch(line)
-			if len(match) > 1 {
-				if percent, err := strconv.ParseFloat(match[1], 64); err == nil {
-					currentProgress := int(percent)
-					if currentProgress != lastProgress {
-						progress <- currentProgress
-						lastProgress = currentProgress
-					}
-				}
-			}
-		}
-	}()
+func (s slogLogger) Debugf(format string, v ...interface{}) { s.l.Debug(fmt.Sprintf(format, v...)) }
+func (s slogLogger) Infof(format string, v ...interface{})  { s.l.Info(fmt.Sprintf(format, v...)) }
+func (s slogLogger) Errorf(format string, v ...interface{}) { s.l.Error(fmt.Sprintf(format, v...)) }
 
-	err = cmd.Wait()
-	if err != nil {
-		logError("yt-dlp command failed: %v", err)
-		
-		// If Instagram fails, provide special error message
-		if service == "Instagram" {
-			return "", fmt.Errorf("Instagram yuklab olishda xatolik. Login ma'lumotlar kerak")
-		}
-		
-		return "", err
-	}
+// newDownloaders registers the download backends for each service. yt-dlp
+// handles everything; Instagram falls back to the API when yt-dlp fails and
+// a RapidAPI key is configured.
+func newDownloaders() *downloader.Registry {
+	ytdlp := downloader.NewYtDlp(slogLogger{logger})
 
-	// Search for any video files in the download directory
-	videoFiles, _ := filepath.Glob(downloadDir + "/*.mp4")
-	if len(videoFiles) > 0 {
-		logInfo("Found MP4 file: %s", videoFiles[0])
-		return videoFiles[0], nil
-	}
-	
-	// Look for other video formats
-	otherVideoFormats := []string{"*.webm", "*.mkv", "*.mov", "*.avi"}
-	for _, format := range otherVideoFormats {
-		files, _ := filepath.Glob(downloadDir + "/" + format)
-		if len(files) > 0 {
-			inputFile := files[0]
-			outputFile := strings.TrimSuffix(inputFile, filepath.Ext(inputFile)) + ".mp4"
-			
-			logInfo("Converting %s to MP4 format", inputFile)
-			
-			convertCmd := exec.Command("ffmpeg", "-i", inputFile, "-c:v", "libx264", "-preset", "fast", "-c:a", "aac", "-b:a", "192k", outputFile)
-			convertOutput, convertErr := convertCmd.CombinedOutput()
-			
-			if convertErr != nil {
-				logError("Conversion failed: %v\nOutput: %s", convertErr, string(convertOutput))
-				// Try to use original file if conversion fails
-				return inputFile, nil
-			}
-			
-			logInfo("Converted to MP4: %s", outputFile)
-			return outputFile, nil
-		}
+	registry := downloader.NewRegistry()
+	registry.SetDefault(ytdlp)
+	registry.Register("Instagram", ytdlp)
+	if cfg.RapidAPIKey != "" {
+		registry.Register("Instagram", downloader.NewInstagramAPI(cfg.RapidAPIKey, slogLogger{logger}))
 	}
-	
-	// Look for any other files
-	allFiles, _ := filepath.Glob(downloadDir + "/*")
-	if len(allFiles) > 0 {
-		logInfo("Found non-video file: %s", allFiles[0])
-		return allFiles[0], nil
-	}
-	
-	return "", fmt.Errorf("Yuklab olingan fayl topilmadi")
+	return registry
 }
 
-// Separate function for Instagram direct download
-func downloadInstagramWithAPI(url string, downloadDir string) (string, error) {
-	logInfo("Attempting Instagram direct download via API for: %s", url)
-	
-	// Extract Instagram ID from URL
-	re := regexp.MustCompile(`/reel/([A-Za-z0-9_-]+)`)
-	matches := re.FindStringSubmatch(url)
-	if len(matches) < 2 {
-		return "", fmt.Errorf("Instagram ID topilmadi")
+// newPoller returns the webhook poller in webhook mode and a long poller
+// otherwise. The webhook registers itself with Telegram when the bot starts.
+func newPoller() telebot.Poller {
+	if cfg.Mode != config.ModeWebhook {
+		return &telebot.LongPoller{Timeout: cfg.PollTimeout}
 	}
-	
-	instagramID := matches[1]
-	logInfo("Extracted Instagram ID: %s", instagramID)
-	
-	// Use a different API service to download Instagram content
-	// NOTE: Replace with a real working Instagram API service
-	apiURL := fmt.Sprintf("https://instagram-downloader-download-instagram-videos-stories.p.rapidapi.com/index?url=%s", url)
-	
-	// Create curl command to download using the API
-	outputFile := filepath.Join(downloadDir, instagramID+".mp4")
-	
-	cmd := exec.Command("curl", 
-		"-X", "GET", 
-		"-H", "X-RapidAPI-Key: YOUR_RAPIDAPI_KEY", // Replace with your RapidAPI key
-		"-H", "X-RapidAPI-Host: instagram-downloader-download-instagram-videos-stories.p.rapidapi.com",
-		"-o", outputFile,
-		apiURL)
-	
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		logError("Instagram API download failed: %v\nOutput: %s", err, string(output))
-		return "", err
+
+	webhook := &telebot.Webhook{
+		Listen:      cfg.WebhookListen,
+		SecretToken: cfg.WebhookSecret,
+		Endpoint:    &telebot.WebhookEndpoint{PublicURL: cfg.WebhookURL},
 	}
-	
-	// Check if file exists and has content
-	fileInfo, err := os.Stat(outputFile)
-	if err != nil || fileInfo.Size() == 0 {
-		logError("Instagram API returned empty file or error")
-		return "", fmt.Errorf("Instagram API xatolik")
+	if cfg.WebhookCert != "" {
+		webhook.TLS = &telebot.WebhookTLS{Cert: cfg.WebhookCert, Key: cfg.WebhookKey}
 	}
-	
-	logInfo("Instagram API download successful: %s", outputFile)
-	return outputFile, nil
+	if cfg.WebhookSelfSigned {
+		webhook.Endpoint.Cert = cfg.WebhookCert
+	}
+	return webhook
 }
 
 func main() {
+	var err error
+
 	// Initialize logger
 	initLogger()
+	defer closeLogger()
+
+	importCSV := flag.Bool("import-csv", false, "import the requests.csv files given as arguments into the store and exit")
+	flag.Parse()
+
+	cfg, err = config.NewConfig()
+	if err != nil {
+		logError("Failed to load config: %v", err)
+		return
+	}
+	if err := setupLogger(); err != nil {
+		logError("Failed to set up logging: %v", err)
+		return
+	}
+	logInfo("Config loaded successfully")
+
+	store, err = storage.Open(cfg.StorePath)
+	if err != nil {
+		logError("Failed to open store: %v", err)
+		return
+	}
+	defer store.Close()
+
+	catalog = i18n.New(i18n.Lang(cfg.DefaultLanguage))
+
+	sites, err = services.New(cfg.Services)
+	if err != nil {
+		logError("Failed to load services: %v", err)
+		return
+	}
+	canon = services.NewCanonicalizer(sites, cfg.ResolveMaxHops, cfg.ResolveTimeout)
+
+	fileCache, err = storage.NewFileCache(store, cfg.FileCacheTTL, cfg.FileCacheSize)
+	if err != nil {
+		logError("Failed to open file cache: %v", err)
+		return
+	}
+
+	if *importCSV {
+		if err := importRequestsCSV(flag.Args()); err != nil {
+			logError("Import failed: %v", err)
+		}
+		return
+	}
+
 	logInfo("Starting Media Download Bot")
 	
 	// Check for yt-dlp
@@ -404,17 +366,15 @@ func main() {
 		logInfo("ffmpeg found and working")
 	}
 	
-	cnf, err := config.NewConfig()
-	if err != nil {
-		logError("Failed to load config: %v", err)
-		return
+	// Leave instructions for the admin where the Instagram cookies go
+	if !downloader.CookieExists(cfg.InstagramCookieFile) {
+		logInfo("Instagram cookie file not found, creating a sample file")
+		downloader.WriteSampleInstagramCookie(cfg.InstagramCookieFile)
 	}
-	botToken := cnf.TelegramToken
-	logInfo("Config loaded successfully")
 
 	pref := telebot.Settings{
-		Token:  botToken,
-		Poller: &telebot.LongPoller{Timeout: 12 * time.Second},
+		Token:  cfg.TelegramToken,
+		Poller: newPoller(),
 	}
 
 	bot, err := telebot.NewBot(pref)
@@ -424,63 +384,69 @@ func main() {
 	}
 	logInfo("Bot created successfully")
 
+	if cfg.Mode == config.ModePolling {
+		// A webhook left over from a production run blocks getUpdates
+		if err := bot.RemoveWebhook(); err != nil {
+			logError("Failed to remove webhook: %v", err)
+		}
+		logInfo("Receiving updates by long polling")
+	} else {
+		logInfo("Receiving updates by webhook at %s (listening on %s)", cfg.WebhookURL, cfg.WebhookListen)
+	}
+
+	downloaders = newDownloaders()
+
+	// Jobs get their own context so they can outlive the signal by the
+	// shutdown grace period
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	statusEdits = newEditLimiter(cfg.StatusInterval)
+	jobs = queue.New(cfg.Workers, cfg.QueueSize)
+	probeSlots = make(chan struct{}, cfg.ProbeWorkers)
+	jobs.Start(jobsCtx)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var server *http.Server
+	if cfg.HTTPListen != "" {
+		checker := newHealthChecker(bot)
+		checker.Start(ctx)
+		server = startHTTPServer(checker)
+	}
+
 	// Create downloads directory
-	os.MkdirAll("downloads", os.ModePerm)
+	os.MkdirAll(cfg.DownloadsDir, os.ModePerm)
+
+	// Clear out whatever crashed or killed jobs left behind
+	sweeper := &janitor.Janitor{
+		Dir:      cfg.DownloadsDir,
+		MaxAge:   cfg.DownloadsMaxAge,
+		Quota:    cfg.DownloadsQuotaMB << 20,
+		Interval: cfg.JanitorInterval,
+		InUse: func(path string) bool {
+			_, ok := activeDirs.Load(path)
+			return ok
+		},
+		Log: logger,
+	}
+	go sweeper.Run(ctx)
 
 	// Start komandasi uchun handler
 	bot.Handle("/start", func(c telebot.Context) error {
 		user := c.Sender()
 		logInfo("User %d (@%s) sent /start command", user.ID, user.Username)
 		
-		welcomeMsg := `🎉 Assalomu alaykum! Media Download botiga xush kelibsiz! 
-
-📱 Men sizga quyidagi xizmatlarni taqdim etaman:
-- YouTube video/audio
-- Instagram post/reels
-- TikTok video
-- Facebook video
-
-🔍 Ishlash tartibi:
-1. Yuklab olmoqchi bo'lgan link/URL ni yuboring
-2. Men sizga faylni yuklab beraman!
-
-⚡️ Tezkor va ishonchli xizmat kafolati bilan!
-
-🤖 Bot @media_download_any_bot`
-
 		// Sticker yuborish
 		sticker := &telebot.Sticker{File: telebot.File{FileID: "CAACAgIAAxkBAAEBuhplOYW_AAFAaNNv-7rjG-QnNJlorgkAAmUBAAIw1J0RZQ1MeHG3J0I0BA"}}
 		c.Send(sticker)
 
-		return c.Send(welcomeMsg)
+		return c.Send(tr(c, i18n.Welcome))
 	})
 
-	// Admin command to set Instagram cookies
-	bot.Handle("/setcookies", func(c telebot.Context) error {
-		user := c.Sender()
-		logInfo("User %d (@%s) tried to set cookies", user.ID, user.Username)
-		
-		// Check if user is admin (add your admin user IDs here)
-		if user.ID != 12345 && user.ID != 67890 { // Replace with your admin user IDs
-			return c.Send("⛔️ Bu buyruq faqat adminlar uchun.")
-		}
-		
-		// Get cookie content from message
-		cookieText := strings.TrimPrefix(c.Text(), "/setcookies ")
-		if cookieText == "/setcookies" || cookieText == "" {
-			return c.Send("❌ Xato format. /setcookies [cookie_matn] ko'rinishida yuboring.")
-		}
-		
-		// Save to cookie file
-		err := ioutil.WriteFile(instagramCookieFile, []byte(cookieText), 0644)
-		if err != nil {
-			logError("Failed to write cookie file: %v", err)
-			return c.Send("❌ Cookie faylni saqlashda xatolik yuz berdi.")
-		}
-		
-		logInfo("Instagram cookies updated successfully")
-		return c.Send("✅ Instagram cookies muvaffaqiyatli yangilandi!")
-	})
+	registerAdminCommands(bot, jobs, cfg.AdminIDs)
+	logInfo("Registered admin commands for %d admins", len(cfg.AdminIDs))
+
+	bot.Handle("/language", handleLanguage)
 
 	bot.Handle("/version", func(c telebot.Context) error {
 		user := c.Sender()
@@ -512,7 +478,7 @@ func main() {
 		}
 		
 		// Add Instagram cookie status
-		if instagramCookieExists() {
+		if downloader.CookieExists(cfg.InstagramCookieFile) {
 			versionText += "\nInstagram cookies: Configured"
 		} else {
 			versionText += "\nInstagram cookies: Not configured"
@@ -521,182 +487,20 @@ func main() {
 		return c.Send(versionText)
 	})
 
-	bot.Handle(telebot.OnText, func(c telebot.Context) error {
-		user := c.Sender()
-		url := c.Text()
-		
-		logInfo("Received URL from User %d (@%s): %s", user.ID, user.Username, url)
-		
-		if isRateLimited(user.ID) {
-			logInfo("User %d (@%s) is rate limited", user.ID, user.Username)
-			return c.Send("⚠️ Siz vaqtinchalik bloklandingiz yoki juda ko'p so'rov yubordingiz. Iltimos, keyinroq urinib ko'ring.")
-		}
-
-		// URL haqiqatdan ham to'g'rimi?
-		if !isValidURL(url) {
-			logInfo("User %d (@%s) sent invalid URL: %s", user.ID, user.Username, url)
-			return c.Send("❌ Iltimos, to'g'ri URL manzil yuboring! Masalan: https://example.com/video")
-		}
-
-		logRequest(user, url)
+	bot.Handle("/audio", handleAudio)
+	bot.Handle(qualityButton, handleQualityPick)
+	bot.Handle("/cancel", handleCancel)
+	bot.Handle(cancelButton, handleCancelButton)
 
-		statusMsg, err := c.Bot().Send(c.Chat(), "🔄 URL tekshirilmoqda...")
-		if err != nil {
-			logError("Failed to send initial status message: %v", err)
-			return err
-		}
-
-		service := getServiceType(url)
-		c.Bot().Edit(statusMsg, fmt.Sprintf("🔍 %s dan media yuklab olinmoqda...", service))
-
-		progress := make(chan int)
-		done := make(chan bool)
-
-		go func() {
-			lastProgress := -1
-			for p := range progress {
-				if p != lastProgress {
-					progressMsg := fmt.Sprintf("⏳ %s dan yuklanmoqda... %d%%", service, p)
-					c.Bot().Edit(statusMsg, progressMsg)
-					logInfo("Download progress for User %d: %d%%", user.ID, p)
-					lastProgress = p
-				}
-			}
-			done <- true
-		}()
-
-		// Create a download directory
-		downloadID := fmt.Sprintf("%d_%d", user.ID, time.Now().Unix())
-		downloadDir := fmt.Sprintf("downloads/%s", downloadID)
-		os.MkdirAll(downloadDir, os.ModePerm)
-
-		var filePath string
-		
-		if service == "Instagram" {
-			// First try with yt-dlp 
-			filePath, err = downloadMedia(user.ID, user.Username, url, progress)
-			if err != nil {
-				logInfo("yt-dlp failed for Instagram, trying alternative method")
-				
-				// If yt-dlp fails for Instagram, try the alternative API method
-				close(progress)
-				<-done
-				
-				// Reset progress tracking for alternative method
-				progress = make(chan int)
-				done = make(chan bool)
-				
-				go func() {
-					// Simulate progress for API method
-					for p := 0; p <= 100; p += 10 {
-						progress <- p
-						time.Sleep(500 * time.Millisecond)
-					}
-					close(progress)
-				}()
-				
-				// Try alternative API
-				filePath, err = downloadInstagramWithAPI(url, downloadDir)
-				<-done
-			} else {
-				close(progress)
-				<-done
-			}
-		} else {
-			// For other services, use the standard method
-			filePath, err = downloadMedia(user.ID, user.Username, url, progress)
-			close(progress)
-			<-done
-		}
-
-		if err != nil {
-			logError("Download failed for User %d (@%s): %v", user.ID, user.Username, err)
-			
-			if service == "Instagram" {
-				return c.Send(`❌ Instagram video yuklab olishda xatolik yuz berdi.
-
-Instagram himoya tizimi tufayli, login ma'lumotlar talab qilinadi.
-
-Administratorga murojaat qiling.`)
-			}
-			
-			return c.Send(fmt.Sprintf("❌ Xatolik: faylni yuklab bo'lmadi. Xato: %v", err))
-		}
-
-		c.Bot().Edit(statusMsg, "✅ Fayl muvaffaqiyatli yuklandi! Yuborilmoqda...")
-		logInfo("Successfully downloaded file for User %d: %s", user.ID, filePath)
-
-		// Get file info
-		fileInfo, err := os.Stat(filePath)
-		if err != nil {
-			logError("Failed to get file info: %v", err)
-		} else {
-			fileSizeMB := float64(fileInfo.Size()) / 1024.0 / 1024.0
-			logInfo("File size: %.2f MB", fileSizeMB)
-		}
-
-		// Determine file type and send appropriately
-		fileExt := strings.ToLower(filepath.Ext(filePath))
-		
-		if fileExt == ".mp4" || fileExt == ".mov" || fileExt == ".avi" || fileExt == ".mkv" || fileExt == ".webm" {
-			// Send as video
-			video := &telebot.Video{
-				File:    telebot.FromDisk(filePath),
-				Caption: "✨ @media_download_any_bot orqali yuklab olindi",
-			}
-			
-			err = c.Send(video)
-			if err != nil {
-				logError("Failed to send video to User %d: %v", user.ID, err)
-				
-				// File might be too large, try sending as document
-				logInfo("Trying to send as document instead")
-				doc := &telebot.Document{
-					File:    telebot.FromDisk(filePath),
-					Caption: "✨ @media_download_any_bot orqali yuklab olindi",
-				}
-				
-				docErr := c.Send(doc)
-				if docErr != nil {
-					logError("Failed to send document: %v", docErr)
-					return c.Send("❌ Xatolik: faylni yuborib bo'lmadi. Hajmi juda katta bo'lishi mumkin.")
-				}
-			}
-		} else if fileExt == ".mp3" || fileExt == ".m4a" || fileExt == ".ogg" || fileExt == ".wav" {
-			// Send as audio
-			audio := &telebot.Audio{
-				File:    telebot.FromDisk(filePath),
-				Caption: "✨ @media_download_any_bot orqali yuklab olindi",
-			}
-			
-			err = c.Send(audio)
-			if err != nil {
-				logError("Failed to send audio: %v", err)
-				return c.Send("❌ Xatolik: faylni yuborib bo'lmadi.")
-			}
-		} else {
-			// Send any other file type as document
-			doc := &telebot.Document{
-				File:    telebot.FromDisk(filePath),
-				Caption: "✨ @media_download_any_bot orqali yuklab olindi",
-			}
-			
-			err = c.Send(doc)
-			if err != nil {
-				logError("Failed to send document: %v", err)
-				return c.Send("❌ Xatolik: faylni yuborib bo'lmadi.")
-			}
-		}
-		
-		logInfo("Successfully sent media to User %d (@%s)", user.ID, user.Username)
-
-		// Clean up the file
-		os.Remove(filePath)
-		logInfo("Removed file: %s", filePath)
-		return nil
-	})
+	bot.Handle(telebot.OnText, handleMessage)
+	bot.Handle(telebot.OnMedia, handleMessage)
 
 	logInfo("Bot started successfully! 🚀")
 	fmt.Println("Bot muvaffaqiyatli ishga tushdi! 🚀")
-	bot.Start()
+	go bot.Start()
+
+	<-ctx.Done()
+	stop()
+	shutdown(bot, server, cfg.ShutdownGrace, stopJobs)
+	logInfo("Bot stopped")
 }
\ No newline at end of file
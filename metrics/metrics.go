@@ -0,0 +1,105 @@
+// Package metrics defines the Prometheus metrics the bot exports on
+// /metrics.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "bot"
+
+// Failure classes used as the class label of DownloadFailures. A download
+// whose error was classified is labelled with the downloader failure
+// instead, such as "private" or "geo_blocked".
+const (
+	ClassDownload = "download"
+	ClassNoMedia  = "no_media"
+	ClassUpload   = "upload"
+	ClassTimeout  = "timeout"
+	ClassCanceled = "canceled"
+	ClassAborted  = "aborted"
+)
+
+var (
+	Requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "requests_total",
+		Help:      "Download requests accepted, by service.",
+	}, []string{"service"})
+
+	RateLimited = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Requests rejected because the user was rate limited or banned.",
+	})
+
+	DownloadSuccesses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "download_successes_total",
+		Help:      "Jobs whose media reached the user, by service.",
+	}, []string{"service"})
+
+	DownloadFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "download_failures_total",
+		Help:      "Jobs that did not deliver media, by service and failure class.",
+	}, []string{"service", "class"})
+
+	DownloadDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "download_duration_seconds",
+		Help:      "Time spent downloading, conversion included, by service.",
+		Buckets:   []float64{1, 2.5, 5, 10, 20, 30, 60, 120, 300, 600},
+	}, []string{"service"})
+
+	ConversionDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "conversion_duration_seconds",
+		Help:      "Time ffmpeg spent converting a video to MP4.",
+		Buckets:   []float64{1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+	})
+
+	UploadDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upload_duration_seconds",
+		Help:      "Time spent sending the result to Telegram, by service.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120},
+	}, []string{"service"})
+
+	FileSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "file_size_bytes",
+		Help:      "Total size of the files downloaded for a job, by service.",
+		// 256 KiB up to 1 GiB
+		Buckets: prometheus.ExponentialBuckets(256<<10, 4, 7),
+	}, []string{"service"})
+)
+
+// RegisterGauges exports the current queue depth, busy workers and banned
+// users, read from the given functions on every scrape.
+func RegisterGauges(queueDepth, activeWorkers, bannedUsers func() float64) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_depth",
+		Help:      "Jobs waiting for a worker.",
+	}, queueDepth)
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_workers",
+		Help:      "Workers currently running a job.",
+	}, activeWorkers)
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "banned_users",
+		Help:      "Users with an active ban.",
+	}, bannedUsers)
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
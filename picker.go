@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bot/downloader"
+	"bot/i18n"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+
+	"gopkg.in/telebot.v3"
+)
+
+// qualityButton routes taps on the quality keyboard to handleQualityPick.
+var qualityButton = &telebot.Btn{Unique: "quality"}
+
+// pendingPick is a quality keyboard waiting for the user to choose.
+type pendingPick struct {
+	userID    int64
+	url       string
+	service   string
+	requestID uint64
+	qualities []downloader.Quality
+	created   time.Time
+}
+
+var (
+	picks   = make(map[string]*pendingPick)
+	picksMu sync.Mutex
+)
+
+// addPick remembers p and returns the token its buttons carry. Expired
+// picks are dropped on the way.
+func addPick(p *pendingPick) string {
+	buf := make([]byte, 6)
+	rand.Read(buf)
+	token := hex.EncodeToString(buf)
+
+	picksMu.Lock()
+	defer picksMu.Unlock()
+	for t, old := range picks {
+		if time.Since(old.created) > cfg.PickerTTL {
+			delete(picks, t)
+		}
+	}
+	picks[token] = p
+	return token
+}
+
+// takePick returns the pick for token and forgets it. It returns nil if the
+// pick is unknown or has expired.
+func takePick(token string) *pendingPick {
+	picksMu.Lock()
+	defer picksMu.Unlock()
+
+	p, ok := picks[token]
+	if !ok {
+		return nil
+	}
+	delete(picks, token)
+	if time.Since(p.created) > cfg.PickerTTL {
+		return nil
+	}
+	return p
+}
+
+// pickerEnabled reports whether the quality keyboard is shown for service.
+func pickerEnabled(service string) bool {
+	for _, s := range cfg.PickerServices {
+		if s == service {
+			return true
+		}
+	}
+	return false
+}
+
+// offerQualities shows the qualities of info that fit the size limit as an
+// inline keyboard on statusMsg. It returns false if there is nothing to
+// choose from, in which case the caller should download the default format.
+func offerQualities(c telebot.Context, statusMsg *telebot.Message, info *downloader.Info, url, service string, requestID uint64) bool {
+	audio := downloader.Format{Audio: true, AudioCodec: cfg.AudioCodec, AudioBitrate: cfg.AudioBitrate}
+	qualities := withinSizeLimit(downloader.Qualities(info, cfg.PickerHeights, audio))
+	if len(qualities) < 2 {
+		return false
+	}
+	return showQualities(c, statusMsg, tr(c, i18n.ChooseQuality, info.Title), qualities, url, service, requestID)
+}
+
+// showQualities replaces statusMsg with text and a button for each of
+// qualities. It returns false if the keyboard could not be shown.
+func showQualities(c telebot.Context, statusMsg *telebot.Message, text string, qualities []downloader.Quality, url, service string, requestID uint64) bool {
+	user := c.Sender()
+	token := addPick(&pendingPick{
+		userID:    user.ID,
+		url:       url,
+		service:   service,
+		requestID: requestID,
+		qualities: qualities,
+		created:   time.Now(),
+	})
+
+	markup := &telebot.ReplyMarkup{}
+	var rows []telebot.Row
+	for i, q := range qualities {
+		label := q.Label
+		if q.Format.Audio {
+			label = tr(c, i18n.AudioButton)
+		}
+		if q.Size > 0 {
+			label += " ~" + humanBytes(q.Size)
+		}
+		btn := markup.Data(label, qualityButton.Unique, token, strconv.Itoa(i))
+		rows = append(rows, markup.Row(btn))
+	}
+	markup.Inline(rows...)
+
+	logInfo("Offering %d qualities to User %d for %s", len(qualities), user.ID, url)
+	if _, err := c.Bot().Edit(statusMsg, text, markup); err != nil {
+		logError("Failed to show quality keyboard: %v", err)
+		takePick(token)
+		return false
+	}
+	return true
+}
+
+// handleQualityPick queues the download the user chose on the keyboard.
+func handleQualityPick(c telebot.Context) error {
+	args := c.Args()
+	if len(args) != 2 {
+		return c.Respond()
+	}
+
+	picksMu.Lock()
+	p, ok := picks[args[0]]
+	picksMu.Unlock()
+	if ok && p.userID != c.Sender().ID {
+		return c.Respond(&telebot.CallbackResponse{Text: tr(c, i18n.PickNotYours)})
+	}
+
+	p = takePick(args[0])
+	index, err := strconv.Atoi(args[1])
+	if p == nil || err != nil || index < 0 || index >= len(p.qualities) {
+		c.Respond(&telebot.CallbackResponse{Text: tr(c, i18n.PickExpired)})
+		_, err := c.Bot().Edit(c.Message(), tr(c, i18n.PickExpired))
+		return err
+	}
+
+	q := p.qualities[index]
+	logInfo("User %d picked %s for %s", p.userID, q.Format.Key(), p.url)
+	c.Respond(&telebot.CallbackResponse{Text: tr(c, i18n.QualityChosen, q.Label)})
+	if serveCached(c, c.Message(), p.url, q.Format) {
+		return nil
+	}
+	return enqueueDownload(c, c.Message(), p.url, p.service, p.requestID, q.Format, nil)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bot/downloader"
+	"bot/i18n"
+	"context"
+	"time"
+
+	"gopkg.in/telebot.v3"
+)
+
+// preflightEnabled reports whether links of service are probed before
+// they are queued.
+func preflightEnabled(service string) bool {
+	for _, s := range cfg.PreflightServices {
+		if s == service {
+			return true
+		}
+	}
+	return false
+}
+
+// preflight reads the metadata of url in format before it takes up a
+// place in the queue, when service is probed or picker needs the formats.
+// The probe uses the service's cookies and yt-dlp options like the
+// download does. It returns nil otherwise or if the probe fails, and the
+// download goes ahead unchecked.
+func preflight(c telebot.Context, statusMsg *telebot.Message, url, service string, format downloader.Format, picker bool) *downloader.Info {
+	if !picker && !preflightEnabled(service) {
+		return nil
+	}
+	c.Bot().Edit(statusMsg, tr(c, i18n.Probing))
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ProbeTimeout)
+	defer cancel()
+
+	// Probes share a few slots so a burst of links cannot start yt-dlp
+	// without bound
+	select {
+	case probeSlots <- struct{}{}:
+		defer func() { <-probeSlots }()
+	case <-ctx.Done():
+		logError("No probe slot for User %d within %s, not checking %s", c.Sender().ID, cfg.ProbeTimeout, url)
+		return nil
+	}
+
+	info, err := downloaders.Probe(ctx, downloadRequest(url, service, format, ""))
+	if err != nil {
+		logError("Probe failed for User %d: %s: %v", c.Sender().ID, url, err)
+		return nil
+	}
+	return info
+}
+
+// checkLimits turns info down on statusMsg if it is a live stream, longer
+// than MaxDuration or larger than MaxFileSizeMB in format. A link that is
+// only too large is offered in the qualities that fit, if any and offer is
+// set. It reports whether the link was stopped here.
+func checkLimits(c telebot.Context, statusMsg *telebot.Message, info *downloader.Info, url, service string, requestID uint64, format downloader.Format, offer bool) bool {
+	user := c.Sender()
+
+	if info.Live {
+		logInfo("Turning down live stream from User %d: %s", user.ID, url)
+		c.Bot().Edit(statusMsg, tr(c, i18n.LimitLive))
+		return true
+	}
+
+	if cfg.MaxDuration > 0 && info.Duration > cfg.MaxDuration {
+		logInfo("Turning down %s from User %d, it runs for %s", url, user.ID, info.Duration)
+		c.Bot().Edit(statusMsg, tr(c, i18n.LimitDuration, info.Duration.Round(time.Second), cfg.MaxDuration))
+		return true
+	}
+
+	limit := cfg.MaxFileSizeMB << 20
+	size := downloader.EstimateSize(info, format)
+	if limit == 0 || size <= limit {
+		return false
+	}
+	logInfo("Turning down %s from User %d, it is about %s as %s", url, user.ID, humanBytes(size), format.Key())
+
+	// Only offer what is known to fit
+	audio := downloader.Format{Audio: true, AudioCodec: cfg.AudioCodec, AudioBitrate: cfg.AudioBitrate}
+	var smaller []downloader.Quality
+	for _, q := range withinSizeLimit(downloader.Qualities(info, cfg.PickerHeights, audio)) {
+		if q.Size > 0 {
+			smaller = append(smaller, q)
+		}
+	}
+	if offer && len(smaller) > 0 {
+		text := tr(c, i18n.LimitSizeOffer, info.Title, humanBytes(size), humanBytes(limit))
+		if showQualities(c, statusMsg, text, smaller, url, service, requestID) {
+			return true
+		}
+	}
+	c.Bot().Edit(statusMsg, tr(c, i18n.LimitSize, humanBytes(size), humanBytes(limit)))
+	return true
+}
+
+// withinSizeLimit drops the qualities known to be larger than
+// MaxFileSizeMB.
+func withinSizeLimit(qualities []downloader.Quality) []downloader.Quality {
+	limit := cfg.MaxFileSizeMB << 20
+	if limit == 0 {
+		return qualities
+	}
+	var fit []downloader.Quality
+	for _, q := range qualities {
+		if q.Size <= limit {
+			fit = append(fit, q)
+		}
+	}
+	return fit
+}
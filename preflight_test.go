@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bot/downloader"
+	"bot/i18n"
+	"testing"
+)
+
+// oversized is a video too large as it is but with a quality that fits.
+var oversized = downloader.Info{
+	Title: "long video",
+	Size:  1 << 30,
+	Formats: []downloader.MediaFormat{
+		{Height: 360, Video: true, Audio: true, Size: 10 << 20},
+	},
+}
+
+func TestTooLargeLinkOffersSmallerQualities(t *testing.T) {
+	tg, bot := setupBot(t)
+	downloaders = downloader.NewRegistry()
+	downloaders.SetDefault(&downloader.Fake{Info: oversized})
+	startQueue(t, 1)
+
+	if err := handleMessage(userMessage(bot, 42, "https://www.youtube.com/watch?v=abc")); err != nil {
+		t.Fatal(err)
+	}
+	edit, _ := tg.last("editMessageText")
+	want := catalog.T(catalog.Default(), i18n.LimitSizeOffer, oversized.Title, humanBytes(oversized.Size), humanBytes(cfg.MaxFileSizeMB<<20))
+	if edit.Params["text"] != want || edit.Params["reply_markup"] == "" {
+		t.Errorf("status %+v, want the quality offer", edit.Params)
+	}
+}
+
+func TestTooLargeLinkInBatchIsTurnedDown(t *testing.T) {
+	tg, bot := setupBot(t)
+	downloaders = downloader.NewRegistry()
+	downloaders.SetDefault(&downloader.Fake{Info: oversized, Files: map[string][]byte{"clip.mp4": []byte("video")}})
+	startQueue(t, 1)
+
+	if err := handleMessage(userMessage(bot, 42, "https://www.youtube.com/watch?v=abc https://example.com/clip")); err != nil {
+		t.Fatal(err)
+	}
+	drain()
+
+	var turnedDown bool
+	limit := catalog.T(catalog.Default(), i18n.LimitSize, humanBytes(oversized.Size), humanBytes(cfg.MaxFileSizeMB<<20))
+	for _, call := range tg.requests() {
+		if call.Method == "editMessageText" && call.Params["text"] == limit {
+			turnedDown = call.Params["reply_markup"] == ""
+		}
+	}
+	if !turnedDown {
+		t.Errorf("oversized batch link was not turned down without a keyboard; calls %v", tg.methods())
+	}
+	summary, _ := tg.last("sendMessage")
+	if want := catalog.T(catalog.Default(), i18n.BatchSummary, 2, 1, 1); summary.Params["text"] != want {
+		t.Errorf("summary %q, want %q", summary.Params["text"], want)
+	}
+}
@@ -0,0 +1,393 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is returned by Submit when no more jobs can be accepted.
+var ErrQueueFull = errors.New("queue: full")
+
+// ErrClosed is returned by Submit after Close has been called.
+var ErrClosed = errors.New("queue: closed")
+
+// ErrCanceled is the error of a job cancelled before a worker picked it up.
+var ErrCanceled = errors.New("queue: canceled")
+
+// State is the lifecycle state of a job.
+type State int
+
+const (
+	Queued State = iota
+	Running
+	Uploading
+	Done
+	Failed
+	Canceled
+)
+
+func (s State) String() string {
+	switch s {
+	case Queued:
+		return "queued"
+	case Running:
+		return "running"
+	case Uploading:
+		return "uploading"
+	case Done:
+		return "done"
+	case Failed:
+		return "failed"
+	case Canceled:
+		return "canceled"
+	}
+	return "unknown"
+}
+
+// Job is a unit of work executed by one of the queue workers.
+type Job struct {
+	ID        int64
+	RequestID uint64
+	UserID    int64
+	ChatID    int64
+	URL       string
+	Service   string
+	Created   time.Time
+
+	// Run does the actual work. It may move the job to Uploading with
+	// SetState; the queue sets Running, Done and Failed itself.
+	Run func(ctx context.Context, j *Job) error
+
+	// OnPosition is called with the job's 1-based position whenever it
+	// changes while the job is waiting.
+	OnPosition func(position int)
+
+	// OnCancel is called once a cancelled job has stopped: straight away
+	// for a waiting job, after Run returns for a running one.
+	OnCancel func()
+
+	// OnAbort is called for a waiting job dropped by Abort.
+	OnAbort func()
+
+	mu       sync.Mutex
+	state    State
+	err      error
+	started  time.Time
+	cancel   context.CancelFunc
+	canceled bool
+}
+
+func (j *Job) State() State {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.state
+}
+
+func (j *Job) SetState(s State) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state = s
+	if s == Running {
+		j.started = time.Now()
+	}
+}
+
+// Err returns the error the job failed with, if any.
+func (j *Job) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// WasCanceled reports whether Cancel was called for the job, as opposed to
+// its context ending for another reason.
+func (j *Job) WasCanceled() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.canceled
+}
+
+// Started returns when a worker picked the job up.
+func (j *Job) Started() time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.started
+}
+
+// Queue runs jobs on a fixed number of workers and holds at most size
+// waiting jobs.
+type Queue struct {
+	workers int
+	size    int
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []*Job
+	active  map[int64]*Job
+	nextID  int64
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+func New(workers, size int) *Queue {
+	if workers < 1 {
+		workers = 1
+	}
+	q := &Queue{
+		workers: workers,
+		size:    size,
+		active:  make(map[int64]*Job),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// Start launches the workers. Jobs are run with ctx.
+func (q *Queue) Start(ctx context.Context) {
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.worker(ctx)
+	}
+}
+
+// Submit enqueues j and returns its 1-based position in the queue.
+func (q *Queue) Submit(j *Job) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return 0, ErrClosed
+	}
+	if len(q.pending) >= q.size {
+		return 0, ErrQueueFull
+	}
+
+	q.nextID++
+	j.ID = q.nextID
+	j.Created = time.Now()
+	j.state = Queued
+	q.pending = append(q.pending, j)
+	q.cond.Signal()
+	return len(q.pending), nil
+}
+
+// Len returns the number of waiting jobs.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// Active returns the jobs currently being run by a worker.
+func (q *Queue) Active() []*Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs := make([]*Job, 0, len(q.active))
+	for _, j := range q.active {
+		jobs = append(jobs, j)
+	}
+	return jobs
+}
+
+// Pending returns the waiting jobs in queue order.
+func (q *Queue) Pending() []*Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]*Job(nil), q.pending...)
+}
+
+// Job returns the waiting or running job with the given ID, or nil.
+func (q *Queue) Job(id int64) *Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if j, ok := q.active[id]; ok {
+		return j
+	}
+	for _, j := range q.pending {
+		if j.ID == id {
+			return j
+		}
+	}
+	return nil
+}
+
+// Cancel stops the job with the given ID. A waiting job is dropped from the
+// queue; a running one has its context cancelled. Jobs that are already
+// uploading or finished cannot be cancelled and Cancel reports false.
+func (q *Queue) Cancel(id int64) bool {
+	q.mu.Lock()
+
+	for i, j := range q.pending {
+		if j.ID != id {
+			continue
+		}
+		q.pending = append(q.pending[:i:i], q.pending[i+1:]...)
+		behind := append([]*Job(nil), q.pending[i:]...)
+		q.mu.Unlock()
+
+		j.mu.Lock()
+		j.canceled = true
+		j.state = Canceled
+		j.err = ErrCanceled
+		j.mu.Unlock()
+
+		// Everyone behind j moved up by one.
+		for k, w := range behind {
+			if w.OnPosition != nil {
+				w.OnPosition(i + k + 1)
+			}
+		}
+		if j.OnCancel != nil {
+			j.OnCancel()
+		}
+		return true
+	}
+
+	defer q.mu.Unlock()
+	j, ok := q.active[id]
+	if !ok {
+		return false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.state != Running || j.canceled {
+		return false
+	}
+	j.canceled = true
+	j.cancel()
+	return true
+}
+
+// CancelUser cancels every waiting or running job of the user and returns
+// how many were cancelled.
+func (q *Queue) CancelUser(userID int64) int {
+	q.mu.Lock()
+	var ids []int64
+	for _, j := range q.active {
+		if j.UserID == userID {
+			ids = append(ids, j.ID)
+		}
+	}
+	for _, j := range q.pending {
+		if j.UserID == userID {
+			ids = append(ids, j.ID)
+		}
+	}
+	q.mu.Unlock()
+
+	n := 0
+	for _, id := range ids {
+		if q.Cancel(id) {
+			n++
+		}
+	}
+	return n
+}
+
+// Close stops accepting jobs and lets the workers exit once the queue is
+// drained. It does not wait for them; see Wait.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.cond.Broadcast()
+}
+
+// Abort closes the queue and drops every waiting job, failing it with
+// ErrClosed and calling its OnAbort. Running jobs carry on; the workers
+// exit once they finish. It returns how many jobs were dropped.
+func (q *Queue) Abort() int {
+	q.mu.Lock()
+	q.closed = true
+	dropped := q.pending
+	q.pending = nil
+	q.mu.Unlock()
+	q.cond.Broadcast()
+
+	for _, j := range dropped {
+		j.mu.Lock()
+		j.state = Failed
+		j.err = ErrClosed
+		j.mu.Unlock()
+
+		if j.OnAbort != nil {
+			j.OnAbort()
+		}
+	}
+	return len(dropped)
+}
+
+// Wait blocks until every worker has exited.
+func (q *Queue) Wait() {
+	q.wg.Wait()
+}
+
+// next takes the first waiting job and marks it running with a context
+// derived from ctx that Cancel can stop.
+func (q *Queue) next(ctx context.Context) (*Job, context.Context, []*Job) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.pending) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.pending) == 0 {
+		return nil, nil, nil
+	}
+
+	j := q.pending[0]
+	q.pending = q.pending[1:]
+	q.active[j.ID] = j
+
+	jctx, cancel := context.WithCancel(ctx)
+	j.mu.Lock()
+	j.cancel = cancel
+	j.state = Running
+	j.started = time.Now()
+	j.mu.Unlock()
+	return j, jctx, append([]*Job(nil), q.pending...)
+}
+
+func (q *Queue) worker(ctx context.Context) {
+	defer q.wg.Done()
+
+	for {
+		j, jctx, waiting := q.next(ctx)
+		if j == nil {
+			return
+		}
+
+		// Everyone behind j moved up by one.
+		for i, w := range waiting {
+			if w.OnPosition != nil {
+				w.OnPosition(i + 1)
+			}
+		}
+
+		err := j.Run(jctx, j)
+
+		j.mu.Lock()
+		j.cancel()
+		j.err = err
+		canceled := j.canceled
+		switch {
+		case canceled:
+			j.state = Canceled
+		case err != nil:
+			j.state = Failed
+		default:
+			j.state = Done
+		}
+		j.mu.Unlock()
+
+		q.mu.Lock()
+		delete(q.active, j.ID)
+		q.mu.Unlock()
+
+		if canceled && j.OnCancel != nil {
+			j.OnCancel()
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingJob returns a job whose Run waits for release or its context and
+// signals started once it runs.
+func blockingJob(started chan<- int64, release <-chan struct{}) *Job {
+	return &Job{Run: func(ctx context.Context, j *Job) error {
+		started <- j.ID
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}}
+}
+
+func TestSubmitPositionsAndFull(t *testing.T) {
+	q := New(1, 2)
+	for want := 1; want <= 2; want++ {
+		pos, err := q.Submit(&Job{Run: func(context.Context, *Job) error { return nil }})
+		if err != nil || pos != want {
+			t.Fatalf("Submit = %d, %v; want %d", pos, err, want)
+		}
+	}
+	if _, err := q.Submit(&Job{}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("got %v, want ErrQueueFull", err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	tests := []struct {
+		name    string
+		running bool
+	}{
+		{"waiting", false},
+		{"running", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := New(1, 10)
+			started := make(chan int64, 2)
+			release := make(chan struct{})
+			defer close(release)
+
+			first := blockingJob(started, release)
+			second := blockingJob(started, release)
+			canceled := make(chan struct{})
+			target := first
+			if !tt.running {
+				target = second
+			}
+			target.OnCancel = func() { close(canceled) }
+
+			q.Submit(first)
+			q.Submit(second)
+			q.Start(context.Background())
+			<-started
+
+			if !q.Cancel(target.ID) {
+				t.Fatal("Cancel returned false")
+			}
+			select {
+			case <-canceled:
+			case <-time.After(time.Second):
+				t.Fatal("OnCancel was not called")
+			}
+			if target.State() != Canceled || !target.WasCanceled() {
+				t.Errorf("state %s, canceled %v", target.State(), target.WasCanceled())
+			}
+			if q.Cancel(target.ID) {
+				t.Error("a job could be cancelled twice")
+			}
+		})
+	}
+}
+
+func TestCancelMovesOthersUp(t *testing.T) {
+	q := New(1, 10)
+	jobs := []*Job{{}, {}, {}}
+	var positions []int
+	jobs[2].OnPosition = func(p int) { positions = append(positions, p) }
+	for _, j := range jobs {
+		q.Submit(j)
+	}
+
+	q.Cancel(jobs[0].ID)
+	if len(positions) != 1 || positions[0] != 2 {
+		t.Errorf("positions %v, want [2]", positions)
+	}
+	if q.Job(jobs[0].ID) != nil {
+		t.Error("cancelled job is still in the queue")
+	}
+}
+
+func TestCancelUser(t *testing.T) {
+	q := New(1, 10)
+	for _, user := range []int64{1, 2, 1, 1} {
+		q.Submit(&Job{UserID: user})
+	}
+	if n := q.CancelUser(1); n != 3 {
+		t.Errorf("cancelled %d jobs, want 3", n)
+	}
+	if q.Len() != 1 || q.Pending()[0].UserID != 2 {
+		t.Errorf("left %d jobs, want only user 2's", q.Len())
+	}
+}
+
+func TestAbort(t *testing.T) {
+	q := New(1, 10)
+	started := make(chan int64, 1)
+	release := make(chan struct{})
+
+	running := blockingJob(started, release)
+	var aborted atomic.Int32
+	waiting := []*Job{{OnAbort: func() { aborted.Add(1) }}, {OnAbort: func() { aborted.Add(1) }}}
+
+	q.Submit(running)
+	for _, j := range waiting {
+		q.Submit(j)
+	}
+	q.Start(context.Background())
+	<-started
+
+	if n := q.Abort(); n != 2 {
+		t.Errorf("Abort dropped %d jobs, want 2", n)
+	}
+	if aborted.Load() != 2 {
+		t.Errorf("OnAbort called %d times, want 2", aborted.Load())
+	}
+	for _, j := range waiting {
+		if j.State() != Failed || !errors.Is(j.Err(), ErrClosed) {
+			t.Errorf("dropped job is %s with %v", j.State(), j.Err())
+		}
+	}
+	if _, err := q.Submit(&Job{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Submit after Abort: %v", err)
+	}
+
+	// The running job carries on and the workers exit after it
+	close(release)
+	q.Wait()
+	if running.State() != Done {
+		t.Errorf("running job ended %s, want done", running.State())
+	}
+}
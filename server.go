@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bot/health"
+	"bot/metrics"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"gopkg.in/telebot.v3"
+)
+
+// newHealthChecker sets up the dependency checks behind /readyz.
+func newHealthChecker(bot *telebot.Bot) *health.Checker {
+	checker := health.New(cfg.HealthInterval, cfg.HealthTimeout)
+	checker.Add("yt-dlp", health.Command("yt-dlp", "--version"))
+	checker.Add("ffmpeg", health.Command("ffmpeg", "-version"))
+	checker.Add("downloads_dir", health.Writable(cfg.DownloadsDir))
+	checker.Add("disk_space", health.FreeSpace(cfg.DownloadsDir, cfg.MinFreeDiskMB<<20))
+	checker.Add("store", func(ctx context.Context) error {
+		return store.Ping()
+	})
+	checker.Add("telegram", func(ctx context.Context) error {
+		_, err := bot.Raw("getMe", nil)
+		return err
+	})
+
+	checker.OnChange = func(s health.Status) {
+		if s.OK {
+			logInfo("Health check %s is passing again", s.Name)
+		} else {
+			logError("Health check %s failed: %s", s.Name, s.Error)
+		}
+	}
+	return checker
+}
+
+// startHTTPServer serves /metrics, /healthz and /readyz on cfg.HTTPListen
+// in the background.
+func startHTTPServer(checker *health.Checker) *http.Server {
+	metrics.RegisterGauges(
+		func() float64 { return float64(jobs.Len()) },
+		func() float64 { return float64(len(jobs.Active())) },
+		func() float64 {
+			bans, err := store.Bans(time.Now())
+			if err != nil {
+				return 0
+			}
+			return float64(len(bans))
+		},
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/healthz", checker.LiveHandler())
+	mux.Handle("/readyz", checker.ReadyHandler())
+
+	server := &http.Server{
+		Addr:              cfg.HTTPListen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logError("HTTP server failed: %v", err)
+		}
+	}()
+	logInfo("Serving /metrics, /healthz and /readyz on %s", cfg.HTTPListen)
+	return server
+}
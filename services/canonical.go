@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// trackingParams are query parameters that never change which media a URL
+// points to. They are stripped from links of unknown services; known ones
+// keep only their KeepParams.
+var trackingParams = []string{"si", "igsh", "igshid", "feature", "fbclid", "_t", "_r"}
+
+// Link is a canonicalized link.
+type Link struct {
+	// URL is the stable form of the link, used in logs and cache keys.
+	URL string
+	// Service is the service the link belongs to, nil if unknown.
+	Service *Service
+	// ID identifies the content within the service, such as a video ID or
+	// a reel shortcode. It is empty if the service has no ID pattern or
+	// the link does not match it.
+	ID string
+}
+
+// Name returns the link's service name, or Unknown.
+func (l Link) Name() string {
+	if l.Service == nil {
+		return Unknown
+	}
+	return l.Service.Name
+}
+
+// Canonicalizer resolves short links and reduces links to their canonical
+// form.
+type Canonicalizer struct {
+	Registry *Registry
+	Client   *http.Client
+	MaxHops  int
+	Timeout  time.Duration
+}
+
+// errTooManyHops stops a redirect chain longer than MaxHops.
+var errTooManyHops = errors.New("too many redirects")
+
+func NewCanonicalizer(r *Registry, maxHops int, timeout time.Duration) *Canonicalizer {
+	c := &Canonicalizer{Registry: r, MaxHops: maxHops, Timeout: timeout}
+	c.Client = &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > c.MaxHops {
+				return errTooManyHops
+			}
+			return nil
+		},
+	}
+	return c
+}
+
+// Canonicalize follows raw if it is a short link, strips tracking
+// parameters and rewrites it to the service's canonical form. If the short
+// link cannot be followed, or leads off the service, it is only cleaned of
+// tracking parameters and the error is returned alongside it.
+func (c *Canonicalizer) Canonicalize(ctx context.Context, raw string) (Link, error) {
+	u, err := parse(raw)
+	if err != nil {
+		return Link{URL: raw}, err
+	}
+
+	s := c.Registry.Match(u.String())
+	if s != nil && s.isShortLink(u.Hostname(), u.Path) {
+		resolved, err := c.resolve(ctx, u.String())
+		if err == nil {
+			var ru *url.URL
+			if ru, err = parse(resolved); err == nil {
+				if rs := c.Registry.Match(ru.String()); rs != nil {
+					u, s = ru, rs
+				} else {
+					err = fmt.Errorf("redirects to %s", resolved)
+				}
+			}
+		}
+		if err != nil {
+			// The short link's token is no content ID, leave it alone
+			clean(u, s)
+			return Link{URL: u.String(), Service: s}, fmt.Errorf("resolve %s: %w", raw, err)
+		}
+	}
+
+	clean(u, s)
+	link := Link{URL: u.String(), Service: s}
+	if s == nil || s.id == nil {
+		return link, nil
+	}
+
+	// Match the ID against host and path so youtu.be/<id> works too
+	if m := s.id.FindStringSubmatch(u.Host + u.RequestURI()); len(m) > 1 {
+		link.ID = m[1]
+		if s.Canonical != "" {
+			link.URL = strings.ReplaceAll(s.Canonical, "{id}", link.ID)
+		}
+	}
+	return link, nil
+}
+
+// resolve returns where raw redirects to. Sites that refuse HEAD are asked
+// again with GET.
+func (c *Canonicalizer) resolve(ctx context.Context, raw string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	var lastErr error
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(ctx, method, raw, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; media-download-bot)")
+
+		resp, err := c.Client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			lastErr = fmt.Errorf("%s %s: %s", method, raw, resp.Status)
+			continue
+		}
+		return resp.Request.URL.String(), nil
+	}
+	return "", lastErr
+}
+
+// parse parses raw, lowercases the host and drops the fragment.
+func parse(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	return u, nil
+}
+
+// clean normalizes a link of s to https without "www." or a trailing slash
+// and drops the query parameters s does not keep. Links of unknown
+// services may depend on their scheme, host and path, so they only lose
+// tracking parameters.
+func clean(u *url.URL, s *Service) {
+	if s != nil {
+		u.Scheme = "https"
+		u.Host = strings.TrimPrefix(u.Host, "www.")
+		u.Path = strings.TrimSuffix(u.Path, "/")
+	}
+
+	q := u.Query()
+	dropped := false
+	for key := range q {
+		switch {
+		case s != nil && !contains(s.KeepParams, key),
+			s == nil && (strings.HasPrefix(key, "utm_") || contains(trackingParams, key)):
+			q.Del(key)
+			dropped = true
+		}
+	}
+	if dropped {
+		u.RawQuery = q.Encode()
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// compileID compiles the service's ID pattern, which must have a capturing
+// group for the ID.
+func compileID(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if re.NumSubexp() < 1 {
+		return nil, fmt.Errorf("id pattern %q has no capturing group", pattern)
+	}
+	return re, nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCanonicalize(t *testing.T) {
+	r, err := New(Defaults("cookies.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCanonicalizer(r, 5, time.Second)
+
+	tests := []struct {
+		raw, want, service, id string
+	}{
+		{"https://youtu.be/dQw4w9WgXcQ?si=abc", "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "YouTube", "dQw4w9WgXcQ"},
+		{"http://m.youtube.com/shorts/dQw4w9WgXcQ", "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "YouTube", "dQw4w9WgXcQ"},
+		{"https://www.instagram.com/reel/Cabc123/?igsh=xyz", "https://www.instagram.com/p/Cabc123/", "Instagram", "Cabc123"},
+		{"https://instagram.com/someone/p/Cabc123/", "https://www.instagram.com/p/Cabc123/", "Instagram", "Cabc123"},
+		{"https://www.tiktok.com/@user/video/123456789/?_t=1", "https://tiktok.com/@user/video/123456789", "TikTok", "123456789"},
+		// Unknown sites keep scheme, host and path; only tracking goes
+		{"http://www.example.com/media/?utm_source=x&id=1", "http://www.example.com/media/?id=1", Unknown, ""},
+		{"http://example.com/a/b/", "http://example.com/a/b/", Unknown, ""},
+	}
+	for _, tt := range tests {
+		link, err := c.Canonicalize(context.Background(), tt.raw)
+		if err != nil {
+			t.Errorf("Canonicalize(%q): %v", tt.raw, err)
+		}
+		if link.URL != tt.want || link.Name() != tt.service || link.ID != tt.id {
+			t.Errorf("Canonicalize(%q) = %q, %s, %q; want %q, %s, %q", tt.raw, link.URL, link.Name(), link.ID, tt.want, tt.service, tt.id)
+		}
+	}
+}
+
+func TestCanonicalizeShortLinks(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/share/reel/good/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://"+r.Host+"/reel/Cabc123/", http.StatusFound)
+	})
+	mux.HandleFunc("/share/reel/broken/", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/reel/", func(w http.ResponseWriter, r *http.Request) {})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	host := srv.Listener.Addr().String()
+
+	r, err := New([]Service{{
+		Name:       "Instagram",
+		Hosts:      []string{"127.0.0.1"},
+		ShortPaths: []string{`^/share/`},
+		ID:         `^[^/]+/(?:[^/]+/)?(?:p|reels?|tv)/([A-Za-z0-9_-]+)`,
+		Canonical:  "https://www.instagram.com/p/{id}/",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCanonicalizer(r, 5, time.Second)
+
+	link, err := c.Canonicalize(context.Background(), "http://"+host+"/share/reel/good/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.URL != "https://www.instagram.com/p/Cabc123/" {
+		t.Errorf("followed share link to %q", link.URL)
+	}
+
+	// A share token is not a shortcode, so an unresolved share link must
+	// not be rewritten
+	link, err = c.Canonicalize(context.Background(), "http://"+host+"/share/reel/broken/")
+	if err == nil {
+		t.Error("expected an error for a share link that does not resolve")
+	}
+	if link.ID != "" || link.URL != "https://"+host+"/share/reel/broken" {
+		t.Errorf("unresolved share link became %q (id %q)", link.URL, link.ID)
+	}
+}
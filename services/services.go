@@ -0,0 +1,232 @@
+// Package services recognises which site a link belongs to by its host
+// name and path, and carries the per-site download options.
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Unknown is the name given to links no service matches.
+const Unknown = "Unknown"
+
+// Service describes one supported site.
+type Service struct {
+	// Name is shown to users and labels logs, metrics and stored requests.
+	Name string `yaml:"name"`
+
+	// Hosts are matched against the link's host name and its parent
+	// domains, so "youtube.com" also covers m.youtube.com and
+	// music.youtube.com.
+	Hosts []string `yaml:"hosts"`
+
+	// Paths, if any, are regular expressions of which the link's path must
+	// match at least one.
+	Paths []string `yaml:"paths"`
+
+	// Enabled defaults to true; links of a disabled service are refused.
+	Enabled *bool `yaml:"enabled"`
+
+	// CookieFile is passed to yt-dlp with --cookies when it exists.
+	CookieFile string `yaml:"cookie_file"`
+
+	// Format is the yt-dlp format selector for the default video
+	// download, replacing the MP4 merge the bot uses otherwise.
+	Format string `yaml:"format"`
+
+	// Args are extra yt-dlp options.
+	Args []string `yaml:"ytdlp_args"`
+
+	// ShortHosts are hosts whose links only redirect to the real page,
+	// such as vm.tiktok.com. They are followed before anything else.
+	ShortHosts []string `yaml:"short_hosts"`
+
+	// ShortPaths are regular expressions for paths that are short links on
+	// the service's own hosts, such as Instagram's /share/ links.
+	ShortPaths []string `yaml:"short_paths"`
+
+	// KeepParams are the query parameters that select the content; all
+	// others are dropped from the canonical link.
+	KeepParams []string `yaml:"keep_params"`
+
+	// ID is a regular expression whose first group extracts the content ID
+	// from the link's host, path and query.
+	ID string `yaml:"id"`
+
+	// Canonical, if set, rewrites links with a known ID to this URL, with
+	// {id} replaced by the ID.
+	Canonical string `yaml:"canonical"`
+
+	paths      []*regexp.Regexp
+	shortPaths []*regexp.Regexp
+	id         *regexp.Regexp
+}
+
+// IsEnabled reports whether links of s are downloaded.
+func (s *Service) IsEnabled() bool {
+	return s.Enabled == nil || *s.Enabled
+}
+
+// isShortLink reports whether a link with host and path only redirects to
+// the real page.
+func (s *Service) isShortLink(host, path string) bool {
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	for _, h := range s.ShortHosts {
+		if host == h {
+			return true
+		}
+	}
+	for _, re := range s.shortPaths {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) matches(host, path string) bool {
+	hostOK := false
+	for _, h := range s.Hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			hostOK = true
+			break
+		}
+	}
+	if !hostOK {
+		return false
+	}
+	if len(s.paths) == 0 {
+		return true
+	}
+	for _, re := range s.paths {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry matches links against a list of services.
+type Registry struct {
+	services []*Service
+	byName   map[string]*Service
+}
+
+// New checks and compiles list. Services are tried in order, so a more
+// specific entry must come before a broader one for the same host.
+func New(list []Service) (*Registry, error) {
+	r := &Registry{byName: make(map[string]*Service)}
+
+	var errs []error
+	for i := range list {
+		s := list[i]
+		if s.Name == "" {
+			errs = append(errs, fmt.Errorf("service #%d has no name", i+1))
+			continue
+		}
+		if _, dup := r.byName[s.Name]; dup || s.Name == Unknown {
+			errs = append(errs, fmt.Errorf("service %q is defined twice or uses a reserved name", s.Name))
+			continue
+		}
+		if len(s.Hosts) == 0 {
+			errs = append(errs, fmt.Errorf("service %q has no hosts", s.Name))
+		}
+		s.Hosts = append([]string(nil), s.Hosts...)
+		for j, h := range s.Hosts {
+			s.Hosts[j] = strings.TrimPrefix(strings.ToLower(h), "www.")
+		}
+		for _, p := range s.Paths {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("service %q: %w", s.Name, err))
+				continue
+			}
+			s.paths = append(s.paths, re)
+		}
+		for _, p := range s.ShortPaths {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("service %q: %w", s.Name, err))
+				continue
+			}
+			s.shortPaths = append(s.shortPaths, re)
+		}
+		if s.ID != "" {
+			re, err := compileID(s.ID)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("service %q: %w", s.Name, err))
+			}
+			s.id = re
+		}
+
+		r.services = append(r.services, &s)
+		r.byName[s.Name] = &s
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Match returns the first service rawURL belongs to, or nil.
+func (r *Registry) Match(rawURL string) *Service {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	for _, s := range r.services {
+		if s.matches(host, u.Path) {
+			return s
+		}
+	}
+	return nil
+}
+
+// Get returns the service called name, or nil.
+func (r *Registry) Get(name string) *Service {
+	return r.byName[name]
+}
+
+// Defaults are the services the bot knows without configuration.
+// instagramCookieFile is the cookie file for Instagram.
+func Defaults(instagramCookieFile string) []Service {
+	return []Service{
+		{
+			Name:  "YouTube",
+			Hosts: []string{"youtube.com", "youtu.be", "youtube-nocookie.com"},
+			// A video inside a playlist link should fetch just that video
+			Args:       []string{"--no-playlist"},
+			KeepParams: []string{"v", "list"},
+			ID:         `(?:youtu\.be/|[?&]v=|/shorts/|/live/|/embed/)([A-Za-z0-9_-]{11})`,
+			Canonical:  "https://www.youtube.com/watch?v={id}",
+		},
+		{
+			Name:       "Instagram",
+			Hosts:      []string{"instagram.com", "instagr.am"},
+			Paths:      []string{`^/(p|reels?|tv|stories|share)/`, `^/[^/]+/(p|reels?)/`},
+			CookieFile: instagramCookieFile,
+			Format:     "best",
+			// Share links carry a token rather than the shortcode
+			ShortPaths: []string{`^/share/`},
+			ID:         `^[^/]+/(?:[^/]+/)?(?:p|reels?|tv)/([A-Za-z0-9_-]+)`,
+			Canonical:  "https://www.instagram.com/p/{id}/",
+		},
+		{
+			Name:       "TikTok",
+			Hosts:      []string{"tiktok.com"},
+			ShortHosts: []string{"vm.tiktok.com", "vt.tiktok.com"},
+			ID:         `/video/(\d+)`,
+		},
+		{
+			Name:       "Facebook",
+			Hosts:      []string{"facebook.com", "fb.com", "fb.watch"},
+			ShortHosts: []string{"fb.watch"},
+			KeepParams: []string{"v", "story_fbid", "id"},
+			ID:         `(?:[?&]v=|/videos/|/reel/)(\d+)`,
+		},
+	}
+}
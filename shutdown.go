@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"gopkg.in/telebot.v3"
+)
+
+// statusFlushTimeout bounds how long shutdown waits for the last status
+// message edits once the jobs are done.
+const statusFlushTimeout = 10 * time.Second
+
+// shutdown stops taking updates, drops queued jobs and gives running ones
+// up to grace to finish. Jobs still running after that are cancelled with
+// stopJobs and their users told to try again, and status messages get up
+// to statusFlushTimeout to show how each job ended. server, if not nil, is
+// closed last.
+func shutdown(bot *telebot.Bot, server *http.Server, grace time.Duration, stopJobs context.CancelFunc) {
+	logInfo("Shutting down, no longer accepting updates")
+	bot.Stop()
+
+	if n := jobs.Abort(); n > 0 {
+		logInfo("Dropped %d queued jobs", n)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		jobs.Wait()
+		close(done)
+	}()
+
+	if running := len(jobs.Active()); running > 0 {
+		logInfo("Waiting up to %s for %d running jobs", grace, running)
+	}
+
+	select {
+	case <-done:
+		logInfo("All jobs finished")
+	case <-time.After(grace):
+		logInfo("Grace period is over, aborting %d running jobs", len(jobs.Active()))
+		stopJobs()
+		<-done
+	}
+	stopJobs()
+
+	// Let users see how their jobs ended before the process exits
+	edited := make(chan struct{})
+	go func() {
+		statusUpdates.Wait()
+		close(edited)
+	}()
+	select {
+	case <-edited:
+	case <-time.After(statusFlushTimeout):
+		logInfo("Gave up on status messages still waiting to be edited")
+	}
+
+	if server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}
+}
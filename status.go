@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"gopkg.in/telebot.v3"
+)
+
+// editLimiter spaces out status message edits so that no chat is edited
+// more than once per interval, and none at all while Telegram has asked
+// the bot to back off.
+type editLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     map[int64]time.Time
+}
+
+func newEditLimiter(interval time.Duration) *editLimiter {
+	return &editLimiter{interval: interval, next: make(map[int64]time.Time)}
+}
+
+// reserve books the next free edit slot in chat and returns how long to
+// wait for it.
+func (l *editLimiter) reserve(chat int64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	next := l.next[chat]
+	if next.Before(now) {
+		next = now
+	}
+	l.next[chat] = next.Add(l.interval)
+
+	// Forget chats that have been quiet for a while
+	if len(l.next) > 1024 {
+		for id, t := range l.next {
+			if t.Before(now) {
+				delete(l.next, id)
+			}
+		}
+	}
+	return next.Sub(now)
+}
+
+// backoff keeps chat from being edited for d, as asked by a flood error.
+func (l *editLimiter) backoff(chat int64, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if t := time.Now().Add(d); t.After(l.next[chat]) {
+		l.next[chat] = t
+	}
+}
+
+// statusEdit is a change waiting to be made to a status message.
+type statusEdit struct {
+	text   string
+	opts   []interface{}
+	delete bool
+	final  bool
+}
+
+// statusUpdater owns a status message and edits it through statusEdits.
+// Updates are coalesced: only the latest one waiting for a slot is sent.
+// The final edit, or the deletion, is always delivered, retrying after
+// flood errors, and nothing is sent after it.
+type statusUpdater struct {
+	bot *telebot.Bot
+	msg *telebot.Message
+
+	mu      sync.Mutex
+	pending *statusEdit
+	closed  bool
+	wake    chan struct{}
+}
+
+// newStatusUpdater starts delivering edits of msg. Every updater must end
+// with Final or Delete; shutdown waits for them on statusUpdates.
+func newStatusUpdater(bot *telebot.Bot, msg *telebot.Message) *statusUpdater {
+	s := &statusUpdater{bot: bot, msg: msg, wake: make(chan struct{}, 1)}
+	statusUpdates.Add(1)
+	go s.run()
+	return s
+}
+
+// Update replaces the message text, unless a newer update or the final
+// state arrives before the chat's next edit slot.
+func (s *statusUpdater) Update(text string, opts ...interface{}) {
+	s.set(&statusEdit{text: text, opts: opts})
+}
+
+// Final replaces the message text for the last time, dropping any update
+// still waiting. Without a markup in opts the inline keyboard is removed.
+func (s *statusUpdater) Final(text string, opts ...interface{}) {
+	s.set(&statusEdit{text: text, opts: opts, final: true})
+}
+
+// Delete removes the message, dropping any update still waiting.
+func (s *statusUpdater) Delete() {
+	s.set(&statusEdit{delete: true, final: true})
+}
+
+func (s *statusUpdater) set(e *statusEdit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = e.final
+	s.pending = e
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *statusUpdater) run() {
+	defer statusUpdates.Done()
+	chat := s.msg.Chat.ID
+
+	for range s.wake {
+		time.Sleep(statusEdits.reserve(chat))
+
+		s.mu.Lock()
+		e := s.pending
+		s.pending = nil
+		s.mu.Unlock()
+		if e == nil {
+			continue
+		}
+
+		var err error
+		if e.delete {
+			err = s.bot.Delete(s.msg)
+		} else {
+			_, err = s.bot.Edit(s.msg, e.text, e.opts...)
+		}
+
+		var flood telebot.FloodError
+		if errors.As(err, &flood) {
+			// Try again once Telegram allows it, unless something newer
+			// came in meanwhile
+			logInfo("Status edit in chat %d hit the flood limit, retrying in %ds", chat, flood.RetryAfter)
+			statusEdits.backoff(chat, time.Duration(flood.RetryAfter)*time.Second)
+			s.retry(e)
+			continue
+		}
+		if err != nil && !errors.Is(err, telebot.ErrMessageNotModified) && !errors.Is(err, telebot.ErrSameMessageContent) {
+			logError("Failed to update status message in chat %d: %v", chat, err)
+		}
+		if e.final {
+			return
+		}
+	}
+}
+
+// retry puts e back unless a newer edit is already waiting.
+func (s *statusUpdater) retry(e *statusEdit) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending == nil {
+		s.pending = e
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
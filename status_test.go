@@ -0,0 +1,108 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/telebot.v3"
+)
+
+func TestEditLimiterSpacesEditsPerChat(t *testing.T) {
+	l := newEditLimiter(time.Second)
+	for i, want := range []time.Duration{0, time.Second, 2 * time.Second} {
+		if got := l.reserve(1); got < want-50*time.Millisecond || got > want {
+			t.Errorf("reserve %d waits %s, want %s", i, got, want)
+		}
+	}
+	if got := l.reserve(2); got != 0 {
+		t.Errorf("another chat waits %s", got)
+	}
+
+	l.backoff(2, 5*time.Second)
+	if got := l.reserve(2); got < 4900*time.Millisecond {
+		t.Errorf("after a flood error the chat waits only %s", got)
+	}
+	// A shorter back off does not bring the slot forward
+	l.backoff(2, time.Second)
+	if got := l.reserve(2); got < 5900*time.Millisecond {
+		t.Errorf("a shorter back off moved the slot to %s", got)
+	}
+}
+
+// statusTexts returns the texts the status message was edited to.
+func statusTexts(tg *fakeTelegram) []string {
+	var texts []string
+	for _, call := range tg.requests() {
+		if call.Method == "editMessageText" {
+			texts = append(texts, call.Params["text"])
+		}
+	}
+	return texts
+}
+
+func TestStatusUpdaterCoalescesToLatest(t *testing.T) {
+	tg, bot := setupBot(t)
+	statusEdits = newEditLimiter(200 * time.Millisecond)
+	msg := &telebot.Message{ID: 1, Chat: &telebot.Chat{ID: 42}}
+
+	s := newStatusUpdater(bot, msg)
+	s.Update("10%")
+	time.Sleep(50 * time.Millisecond)
+	// These arrive while the chat waits for its next slot
+	s.Update("20%")
+	s.Update("30%")
+	time.Sleep(400 * time.Millisecond)
+	s.Final("done")
+	s.Update("late")
+	statusUpdates.Wait()
+
+	texts := statusTexts(tg)
+	want := []string{"10%", "30%", "done"}
+	if len(texts) != len(want) {
+		t.Fatalf("edits %q, want %q", texts, want)
+	}
+	for i := range want {
+		if texts[i] != want[i] {
+			t.Fatalf("edits %q, want %q", texts, want)
+		}
+	}
+}
+
+func TestStatusUpdaterFinalReplacesPending(t *testing.T) {
+	tg, bot := setupBot(t)
+	statusEdits = newEditLimiter(200 * time.Millisecond)
+	msg := &telebot.Message{ID: 1, Chat: &telebot.Chat{ID: 42}}
+
+	s := newStatusUpdater(bot, msg)
+	s.Update("10%")
+	time.Sleep(50 * time.Millisecond)
+	// The final state arrives before the update waiting for a slot is sent
+	s.Update("20%")
+	s.Delete()
+	statusUpdates.Wait()
+
+	if texts := statusTexts(tg); len(texts) != 1 || texts[0] != "10%" {
+		t.Errorf("edits %q, want only the first update", texts)
+	}
+	if _, ok := tg.last("deleteMessage"); !ok {
+		t.Errorf("status message was not deleted; calls %v", tg.methods())
+	}
+}
+
+func TestStatusUpdaterRetriesAfterFloodError(t *testing.T) {
+	tg, bot := setupBot(t)
+	tg.fail("editMessageText", 429, "Too Many Requests: retry after 1", 1)
+	msg := &telebot.Message{ID: 1, Chat: &telebot.Chat{ID: 42}}
+
+	start := time.Now()
+	s := newStatusUpdater(bot, msg)
+	s.Final("done")
+	statusUpdates.Wait()
+
+	if texts := statusTexts(tg); len(texts) != 2 || texts[1] != "done" {
+		t.Fatalf("edits %q, want the final state retried once", texts)
+	}
+	if took := time.Since(start); took < time.Second {
+		t.Errorf("retried after %s, before Telegram's retry_after", took)
+	}
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var filesBucket = []byte("files")
+
+// CachedFile is a Telegram file already uploaded for a URL and format.
+type CachedFile struct {
+	Key      string    `json:"key"`
+	FileID   string    `json:"file_id"`
+	Kind     string    `json:"kind"`
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"last_used"`
+	Hits     int       `json:"hits"`
+}
+
+// FileCache maps normalized URL/format keys to Telegram file IDs so repeat
+// requests can be answered without downloading again. Entries expire after
+// ttl and the oldest are evicted once there are more than max.
+type FileCache struct {
+	store *Store
+	ttl   time.Duration
+	max   int
+}
+
+func NewFileCache(s *Store, ttl time.Duration, max int) (*FileCache, error) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(filesBucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &FileCache{store: s, ttl: ttl, max: max}, nil
+}
+
+// Get returns the cached file for key if it exists and has not expired.
+func (c *FileCache) Get(key string) (*CachedFile, bool) {
+	var f CachedFile
+	found := false
+	now := time.Now()
+
+	err := c.store.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(filesBucket)
+		data := b.Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		if err := json.Unmarshal(data, &f); err != nil {
+			return err
+		}
+		if c.ttl > 0 && now.Sub(f.Created) > c.ttl {
+			return b.Delete([]byte(key))
+		}
+
+		found = true
+		f.Hits++
+		f.LastUsed = now
+		return put(b, []byte(key), f)
+	})
+	if err != nil || !found {
+		return nil, false
+	}
+	return &f, true
+}
+
+// Put stores f, evicting the least recently used entries if the cache is
+// over its size cap.
+func (c *FileCache) Put(f CachedFile) error {
+	now := time.Now()
+	f.Created = now
+	f.LastUsed = now
+
+	return c.store.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(filesBucket)
+		if err := put(b, []byte(f.Key), f); err != nil {
+			return err
+		}
+		if c.max <= 0 {
+			return nil
+		}
+
+		// Bucket.Stats misses what this transaction wrote, so the entries
+		// are counted as they are read
+		var entries []CachedFile
+		err := b.ForEach(func(k, v []byte) error {
+			var e CachedFile
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			entries = append(entries, e)
+			return nil
+		})
+		if err != nil || len(entries) <= c.max {
+			return err
+		}
+
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].LastUsed.Before(entries[j].LastUsed)
+		})
+		for _, e := range entries[:len(entries)-c.max] {
+			if err := b.Delete([]byte(e.Key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Purge removes every entry whose key contains match, or all entries if
+// match is empty. It returns the number of entries removed.
+func (c *FileCache) Purge(match string) (int, error) {
+	removed := 0
+	err := c.store.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(filesBucket)
+		var keys [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			if match == "" || strings.Contains(string(k), match) {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(keys)
+		return nil
+	})
+	return removed, err
+}
+
+// Len returns the number of cached entries, including expired ones not yet
+// cleaned up.
+func (c *FileCache) Len() int {
+	n := 0
+	c.store.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(filesBucket).Stats().KeyN
+		return nil
+	})
+	return n
+}
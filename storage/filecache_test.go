@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFileCacheExpires(t *testing.T) {
+	c, err := NewFileCache(openTestStore(t), 50*time.Millisecond, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Put(CachedFile{Key: "video|https://youtu.be/abc", FileID: "f1", Kind: "video"}); err != nil {
+		t.Fatal(err)
+	}
+
+	f, ok := c.Get("video|https://youtu.be/abc")
+	if !ok || f.FileID != "f1" || f.Hits != 1 {
+		t.Fatalf("fresh entry: got %+v, %v", f, ok)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if f, ok := c.Get("video|https://youtu.be/abc"); ok {
+		t.Fatalf("expired entry returned: %+v", f)
+	}
+	if n := c.Len(); n != 0 {
+		t.Errorf("expired entry kept, %d entries", n)
+	}
+}
+
+func TestFileCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c, err := NewFileCache(openTestStore(t), time.Hour, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	put := func(key string) {
+		t.Helper()
+		if err := c.Put(CachedFile{Key: key, FileID: key}); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	put("a")
+	put("b")
+	// Using a makes b the least recently used
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a is missing")
+	}
+	time.Sleep(time.Millisecond)
+	put("c")
+
+	if n := c.Len(); n != 2 {
+		t.Errorf("%d entries, want 2", n)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Error("b was kept, want it evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+}
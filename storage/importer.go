@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvTimeLayout is the timestamp format the old requests.csv log used.
+const csvTimeLayout = "2006-01-02 15:04:05"
+
+// ImportCSV loads rows written by the old requests.csv logger
+// (user ID, username, first name, last name, URL, time in loc) into s.
+// serviceOf classifies each URL. Rows imported before are skipped, so the
+// same file can be imported again safely. It returns the number of
+// imported and skipped rows.
+func ImportCSV(s *Store, r io.Reader, loc *time.Location, serviceOf func(string) string) (imported, skipped int, err error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return imported, skipped, nil
+		}
+		if err != nil {
+			return imported, skipped, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(record) < 6 {
+			return imported, skipped, fmt.Errorf("line %d: expected 6 fields, got %d", line, len(record))
+		}
+
+		userID, err := strconv.ParseInt(record[0], 10, 64)
+		if err != nil {
+			return imported, skipped, fmt.Errorf("line %d: bad user ID %q: %w", line, record[0], err)
+		}
+		at, err := time.ParseInLocation(csvTimeLayout, record[5], loc)
+		if err != nil {
+			return imported, skipped, fmt.Errorf("line %d: bad time %q: %w", line, record[5], err)
+		}
+
+		u := User{ID: userID, Username: record[1], FirstName: record[2], LastName: record[3]}
+		req := &Request{URL: record[4], Service: serviceOf(record[4]), Time: at}
+		added, err := s.addImportedRequest(u, req)
+		if err != nil {
+			return imported, skipped, fmt.Errorf("line %d: %w", line, err)
+		}
+		if !added {
+			skipped++
+			continue
+		}
+		imported++
+	}
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleCSV = `42,alice,Alice,,https://youtu.be/abc,2025-02-01 10:00:00
+42,alice,Alice,,https://instagram.com/p/xyz,2025-02-01 10:05:00
+7,bob,Bob,Smith,https://example.com/v,2025-02-02 08:00:00
+`
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "bot.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestImportCSV(t *testing.T) {
+	s := openTestStore(t)
+	serviceOf := func(url string) string {
+		if strings.Contains(url, "youtu") {
+			return "YouTube"
+		}
+		return "Unknown"
+	}
+
+	imported, skipped, err := ImportCSV(s, strings.NewReader(sampleCSV), time.UTC, serviceOf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 3 || skipped != 0 {
+		t.Fatalf("first import: imported %d, skipped %d; want 3, 0", imported, skipped)
+	}
+
+	// Importing the same file again must not duplicate anything
+	imported, skipped, err = ImportCSV(s, strings.NewReader(sampleCSV), time.UTC, serviceOf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != 0 || skipped != 3 {
+		t.Fatalf("second import: imported %d, skipped %d; want 0, 3", imported, skipped)
+	}
+
+	u, err := s.User(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Requests != 2 || u.Username != "alice" {
+		t.Errorf("user 42 = %+v, want 2 requests from alice", u)
+	}
+	want := time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC)
+	if !u.FirstSeen.Equal(want) {
+		t.Errorf("first seen %s, want %s", u.FirstSeen, want)
+	}
+
+	reqs, err := s.UserRequests(42, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 2 {
+		t.Fatalf("got %d requests for user 42, want 2", len(reqs))
+	}
+	services := map[string]bool{}
+	for _, r := range reqs {
+		services[r.Service] = true
+	}
+	if !services["YouTube"] || !services["Unknown"] {
+		t.Errorf("services not classified: %+v", reqs)
+	}
+}
+
+func TestImportCSVErrors(t *testing.T) {
+	tests := []struct {
+		name, csv string
+	}{
+		{"short row", "42,alice,Alice\n"},
+		{"bad user ID", "x,alice,Alice,,https://a.b,2025-02-01 10:00:00\n"},
+		{"bad time", "42,alice,Alice,,https://a.b,yesterday\n"},
+	}
+	for _, tt := range tests {
+		s := openTestStore(t)
+		_, _, err := ImportCSV(s, strings.NewReader(tt.csv), time.UTC, func(string) string { return "Unknown" })
+		if err == nil || !strings.Contains(err.Error(), "line 1") {
+			t.Errorf("%s: got %v, want an error for line 1", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrNotFound is returned when a record does not exist.
+var ErrNotFound = errors.New("storage: not found")
+
+var (
+	usersBucket     = []byte("users")
+	requestsBucket  = []byte("requests")
+	downloadsBucket = []byte("downloads")
+	bansBucket      = []byte("bans")
+
+	// importedBucket remembers the requests ImportCSV has already stored
+	importedBucket = []byte("imported")
+)
+
+// User is a Telegram user who has talked to the bot.
+type User struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Requests  int       `json:"requests"`
+	Language  string    `json:"language,omitempty"`
+}
+
+// Request is a URL sent to the bot.
+type Request struct {
+	ID      uint64    `json:"id"`
+	UserID  int64     `json:"user_id"`
+	URL     string    `json:"url"`
+	Service string    `json:"service"`
+	Time    time.Time `json:"time"`
+}
+
+// Download is the outcome of processing a request.
+type Download struct {
+	ID        uint64        `json:"id"`
+	RequestID uint64        `json:"request_id"`
+	UserID    int64         `json:"user_id"`
+	Service   string        `json:"service"`
+	Size      int64         `json:"size"`
+	Duration  time.Duration `json:"duration"`
+	Error     string        `json:"error,omitempty"`
+	Time      time.Time     `json:"time"`
+}
+
+// Ban blocks a user from using the bot until Until.
+type Ban struct {
+	UserID  int64     `json:"user_id"`
+	Until   time.Time `json:"until"`
+	Reason  string    `json:"reason"`
+	Created time.Time `json:"created"`
+}
+
+// Active reports whether the ban is still in effect at t.
+func (b Ban) Active(t time.Time) bool {
+	return t.Before(b.Until)
+}
+
+// Store keeps users, requests, download outcomes and bans in a bbolt file.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens or creates the database at path.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("storage: open %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{usersBucket, requestsBucket, downloadsBucket, bansBucket, importedBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("storage: init buckets: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close flushes and closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
+
+func idKey(id int64) []byte {
+	return itob(uint64(id))
+}
+
+func put(b *bolt.Bucket, key []byte, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, data)
+}
+
+// AddRequest stores r, assigning its ID, and creates or updates the
+// requesting user. A zero r.Time is set to now.
+func (s *Store) AddRequest(u User, r *Request) error {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	r.UserID = u.ID
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return addRequest(tx, u, r)
+	})
+}
+
+// addImportedRequest stores r like AddRequest unless a request of the same
+// user, time and URL was imported before. It reports whether r was stored.
+func (s *Store) addImportedRequest(u User, r *Request) (bool, error) {
+	r.UserID = u.ID
+	key := []byte(fmt.Sprintf("%d|%d|%s", u.ID, r.Time.Unix(), r.URL))
+
+	added := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		imported := tx.Bucket(importedBucket)
+		if imported.Get(key) != nil {
+			return nil
+		}
+		if err := addRequest(tx, u, r); err != nil {
+			return err
+		}
+		added = true
+		return imported.Put(key, itob(r.ID))
+	})
+	return added, err
+}
+
+func addRequest(tx *bolt.Tx, u User, r *Request) error {
+	b := tx.Bucket(requestsBucket)
+	id, err := b.NextSequence()
+	if err != nil {
+		return err
+	}
+	r.ID = id
+	if err := put(b, itob(id), r); err != nil {
+		return err
+	}
+	return touchUser(tx.Bucket(usersBucket), u, r.Time)
+}
+
+// touchUser merges u into the stored record and counts one more request.
+func touchUser(b *bolt.Bucket, u User, at time.Time) error {
+	var stored User
+	if data := b.Get(idKey(u.ID)); data != nil {
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return err
+		}
+	} else {
+		stored = User{ID: u.ID, FirstSeen: at}
+	}
+
+	stored.Username = u.Username
+	stored.FirstName = u.FirstName
+	stored.LastName = u.LastName
+	if at.Before(stored.FirstSeen) {
+		stored.FirstSeen = at
+	}
+	if at.After(stored.LastSeen) {
+		stored.LastSeen = at
+	}
+	stored.Requests++
+	return put(b, idKey(u.ID), stored)
+}
+
+// SetLanguage stores the language u picked, creating the user if needed.
+func (s *Store) SetLanguage(u User, lang string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(usersBucket)
+		stored := User{ID: u.ID, Username: u.Username, FirstName: u.FirstName, LastName: u.LastName, FirstSeen: time.Now()}
+		if data := b.Get(idKey(u.ID)); data != nil {
+			if err := json.Unmarshal(data, &stored); err != nil {
+				return err
+			}
+		}
+		stored.Language = lang
+		return put(b, idKey(u.ID), stored)
+	})
+}
+
+// AddDownload stores the outcome of a request.
+func (s *Store) AddDownload(d *Download) error {
+	if d.Time.IsZero() {
+		d.Time = time.Now()
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(downloadsBucket)
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		d.ID = id
+		return put(b, itob(id), d)
+	})
+}
+
+// User returns the stored user with the given ID.
+func (s *Store) User(id int64) (*User, error) {
+	var u User
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(usersBucket).Get(idKey(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, &u)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// UserRequests returns up to limit of the user's most recent requests,
+// newest first.
+func (s *Store) UserRequests(userID int64, limit int) ([]Request, error) {
+	var reqs []Request
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(requestsBucket).Cursor()
+		for k, v := c.Last(); k != nil && len(reqs) < limit; k, v = c.Prev() {
+			var r Request
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+			if r.UserID == userID {
+				reqs = append(reqs, r)
+			}
+		}
+		return nil
+	})
+	return reqs, err
+}
+
+// SetBan stores or replaces the ban for b.UserID.
+func (s *Store) SetBan(b Ban) error {
+	if b.Created.IsZero() {
+		b.Created = time.Now()
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return put(tx.Bucket(bansBucket), idKey(b.UserID), b)
+	})
+}
+
+// DeleteBan lifts the ban on userID.
+func (s *Store) DeleteBan(userID int64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bansBucket).Delete(idKey(userID))
+	})
+}
+
+// Ban returns the stored ban for userID, which may have expired.
+func (s *Store) Ban(userID int64) (*Ban, error) {
+	var b Ban
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bansBucket).Get(idKey(userID))
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, &b)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// Bans returns every ban still active at t.
+func (s *Store) Bans(t time.Time) ([]Ban, error) {
+	var bans []Ban
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bansBucket).ForEach(func(k, v []byte) error {
+			var b Ban
+			if err := json.Unmarshal(v, &b); err != nil {
+				return err
+			}
+			if b.Active(t) {
+				bans = append(bans, b)
+			}
+			return nil
+		})
+	})
+	return bans, err
+}
+
+// Stats summarises activity since a point in time.
+type Stats struct {
+	Users       int
+	ActiveUsers int
+	NewUsers    int
+	Requests    int
+	Downloads   int
+	Failures    int
+	Bytes       int64
+	ByService   map[string]int
+}
+
+// Stats counts users, requests and download outcomes since since.
+func (s *Store) Stats(since time.Time) (*Stats, error) {
+	st := &Stats{ByService: make(map[string]int)}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		err := tx.Bucket(usersBucket).ForEach(func(k, v []byte) error {
+			var u User
+			if err := json.Unmarshal(v, &u); err != nil {
+				return err
+			}
+			st.Users++
+			if !u.LastSeen.Before(since) {
+				st.ActiveUsers++
+			}
+			if !u.FirstSeen.Before(since) {
+				st.NewUsers++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		err = tx.Bucket(requestsBucket).ForEach(func(k, v []byte) error {
+			var r Request
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+			if !r.Time.Before(since) {
+				st.Requests++
+				st.ByService[r.Service]++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket(downloadsBucket).ForEach(func(k, v []byte) error {
+			var d Download
+			if err := json.Unmarshal(v, &d); err != nil {
+				return err
+			}
+			if d.Time.Before(since) {
+				return nil
+			}
+			if d.Error != "" {
+				st.Failures++
+			} else {
+				st.Downloads++
+				st.Bytes += d.Size
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// Ping checks that the database is usable.
+func (s *Store) Ping() error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(usersBucket) == nil {
+			return errors.New("storage: users bucket missing")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bot/config"
+	"bot/i18n"
+	"bot/queue"
+	"bot/services"
+	"bot/storage"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+	"gopkg.in/telebot.v3"
+)
+
+// apiCall is one Bot API request the fake Telegram received.
+type apiCall struct {
+	Method string
+	Params map[string]string // uploads hold the file content
+}
+
+// fakeTelegram answers Bot API requests the way Telegram does and records
+// them. Responses queued with fail are returned instead of the next
+// successful one for their method.
+type fakeTelegram struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	calls  []apiCall
+	nextID int
+	fails  map[string][]string
+}
+
+func newFakeTelegram(t *testing.T) *fakeTelegram {
+	f := &fakeTelegram{fails: make(map[string][]string)}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// fail makes the next call of method answer with a Telegram error.
+func (f *fakeTelegram) fail(method string, code int, description string, retryAfter int) {
+	resp, _ := json.Marshal(map[string]interface{}{
+		"ok":          false,
+		"error_code":  code,
+		"description": description,
+		"parameters":  map[string]int{"retry_after": retryAfter},
+	})
+	f.mu.Lock()
+	f.fails[method] = append(f.fails[method], string(resp))
+	f.mu.Unlock()
+}
+
+// requests returns the calls received so far.
+func (f *fakeTelegram) requests() []apiCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]apiCall(nil), f.calls...)
+}
+
+// methods returns the method of every call received so far.
+func (f *fakeTelegram) methods() []string {
+	var methods []string
+	for _, c := range f.requests() {
+		methods = append(methods, c.Method)
+	}
+	return methods
+}
+
+// last returns the latest call of method.
+func (f *fakeTelegram) last(method string) (apiCall, bool) {
+	calls := f.requests()
+	for i := len(calls) - 1; i >= 0; i-- {
+		if calls[i].Method == method {
+			return calls[i], true
+		}
+	}
+	return apiCall{}, false
+}
+
+func (f *fakeTelegram) serve(w http.ResponseWriter, r *http.Request) {
+	call := apiCall{Method: path.Base(r.URL.Path), Params: make(map[string]string)}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for k, v := range r.MultipartForm.Value {
+			call.Params[k] = v[0]
+		}
+	} else {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		for k, v := range body {
+			call.Params[k] = fmt.Sprint(v)
+		}
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	f.nextID++
+	id := f.nextID
+	var failure string
+	if queued := f.fails[call.Method]; len(queued) > 0 {
+		failure, f.fails[call.Method] = queued[0], queued[1:]
+	}
+	f.mu.Unlock()
+
+	if failure != "" {
+		io.WriteString(w, failure)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": result(call, id)})
+}
+
+// result is what Telegram returns for call: the message sent or edited,
+// with a file ID for uploads.
+func result(call apiCall, id int) interface{} {
+	chat, _ := strconv.ParseInt(call.Params["chat_id"], 10, 64)
+	msg := map[string]interface{}{
+		"message_id": id,
+		"date":       time.Now().Unix(),
+		"chat":       map[string]interface{}{"id": chat, "type": "private"},
+		"text":       call.Params["text"],
+	}
+	file := map[string]string{"file_id": fmt.Sprintf("file-%d", id)}
+
+	switch call.Method {
+	case "deleteMessage", "answerCallbackQuery":
+		return true
+	case "sendMediaGroup":
+		return []interface{}{msg}
+	case "sendVideo":
+		msg["video"] = file
+	case "sendPhoto":
+		msg["photo"] = []interface{}{file}
+	case "sendAudio":
+		msg["audio"] = file
+	case "sendDocument":
+		msg["document"] = file
+	}
+	return msg
+}
+
+// setupBot points every global the handlers use at a fresh test
+// environment: default settings, a store in a temporary directory and a
+// bot talking to a fake Telegram. Download backends and the queue are up
+// to the test.
+func setupBot(t *testing.T) (*fakeTelegram, *telebot.Bot) {
+	t.Helper()
+	t.Setenv("TELEGRAMTOKEN", "test")
+
+	cfg = &config.Config{}
+	if err := cleanenv.ReadEnv(cfg); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	cfg.DownloadsDir = filepath.Join(dir, "downloads")
+	cfg.StorePath = filepath.Join(dir, "bot.db")
+	cfg.StatusInterval = 10 * time.Millisecond
+	cfg.Services = services.Defaults("")
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	userRequests = make(map[int64][]time.Time)
+	bannedUsers = make(map[int64]time.Time)
+
+	var err error
+	if store, err = storage.Open(cfg.StorePath); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if fileCache, err = storage.NewFileCache(store, cfg.FileCacheTTL, cfg.FileCacheSize); err != nil {
+		t.Fatal(err)
+	}
+	catalog = i18n.New(i18n.Lang(cfg.DefaultLanguage))
+	if sites, err = services.New(cfg.Services); err != nil {
+		t.Fatal(err)
+	}
+	canon = services.NewCanonicalizer(sites, cfg.ResolveMaxHops, cfg.ResolveTimeout)
+	statusEdits = newEditLimiter(cfg.StatusInterval)
+	probeSlots = make(chan struct{}, cfg.ProbeWorkers)
+
+	tg := newFakeTelegram(t)
+	bot, err := telebot.NewBot(telebot.Settings{URL: tg.URL, Token: "test", Offline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tg, bot
+}
+
+// startQueue starts the job queue with workers.
+func startQueue(t *testing.T, workers int) {
+	t.Helper()
+	jobs = queue.New(workers, cfg.QueueSize)
+	jobs.Start(context.Background())
+	t.Cleanup(func() {
+		jobs.Abort()
+		jobs.Wait()
+	})
+}
+
+// drain lets the queued jobs finish and waits until their status messages
+// are final.
+func drain() {
+	jobs.Close()
+	jobs.Wait()
+	statusUpdates.Wait()
+}
+
+// userMessage is a private chat message with text from user.
+func userMessage(bot *telebot.Bot, user int64, text string) telebot.Context {
+	return bot.NewContext(telebot.Update{Message: &telebot.Message{
+		ID:     1,
+		Sender: &telebot.User{ID: user, Username: fmt.Sprintf("user%d", user)},
+		Chat:   &telebot.Chat{ID: user, Type: telebot.ChatPrivate},
+		Text:   text,
+	}})
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bot/downloader"
+	"bot/i18n"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gopkg.in/telebot.v3"
+)
+
+// urlRe finds links in text Telegram did not mark up as entities.
+var urlRe = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// extractURLs returns the links in m's text or caption in order, without
+// duplicates. Telegram's url and text_link entities are used when present,
+// otherwise the text is scanned.
+func extractURLs(m *telebot.Message) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	add := func(u string) {
+		u = strings.TrimSpace(u)
+		if u == "" || seen[u] {
+			return
+		}
+		seen[u] = true
+		urls = append(urls, u)
+	}
+
+	for _, entities := range [][]telebot.MessageEntity{m.Entities, m.CaptionEntities} {
+		for _, e := range entities {
+			switch e.Type {
+			case telebot.EntityURL:
+				// Bare domains like vm.tiktok.com/xyz are marked up too
+				u := m.EntityText(e)
+				if !strings.Contains(u, "://") {
+					u = "https://" + u
+				}
+				add(u)
+			case telebot.EntityTextLink:
+				add(e.URL)
+			}
+		}
+	}
+	if len(urls) > 0 {
+		return urls
+	}
+
+	for _, match := range urlRe.FindAllString(m.Text+"\n"+m.Caption, -1) {
+		add(strings.TrimRight(match, ".,;:!?)]}'»"))
+	}
+	return urls
+}
+
+// handleMessage downloads every link in a text message or media caption.
+// Several links become a batch of jobs with a summary once all are done.
+func handleMessage(c telebot.Context) error {
+	urls := extractURLs(c.Message())
+	switch {
+	case len(urls) == 0 && c.Message().Text != "":
+		// Let handleDownload explain what a link looks like
+		return handleDownload(c, c.Message().Text, downloader.Format{})
+	case len(urls) == 0:
+		// Media captioned without a link is not meant for the bot
+		return nil
+	case len(urls) == 1:
+		return handleDownload(c, urls[0], downloader.Format{})
+	}
+
+	user := c.Sender()
+	if len(urls) > cfg.MaxURLs {
+		logInfo("User %d (@%s) sent %d URLs, keeping the first %d", user.ID, user.Username, len(urls), cfg.MaxURLs)
+		c.Send(tr(c, i18n.TooManyURLs, cfg.MaxURLs))
+		urls = urls[:cfg.MaxURLs]
+	}
+
+	if rejectRateLimited(c) {
+		return nil
+	}
+
+	logInfo("Received %d URLs from User %d (@%s)", len(urls), user.ID, user.Username)
+	b := &batch{c: c, left: len(urls)}
+	for _, u := range urls {
+		if err := startDownload(c, u, downloader.Format{}, b); err != nil {
+			logError("Could not start download of %s for User %d: %v", u, user.ID, err)
+		}
+	}
+	return nil
+}
+
+// batch counts the outcomes of the jobs started for one message and sends
+// a summary when the last one finishes. A nil batch ignores outcomes.
+type batch struct {
+	c telebot.Context
+
+	mu         sync.Mutex
+	left       int
+	ok, failed int
+}
+
+// finish records the outcome of one job.
+func (b *batch) finish(ok bool) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	if ok {
+		b.ok++
+	} else {
+		b.failed++
+	}
+	b.left--
+	last := b.left == 0
+	b.mu.Unlock()
+
+	if last {
+		b.c.Send(tr(b.c, i18n.BatchSummary, b.ok+b.failed, b.ok, b.failed))
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bot/i18n"
+	"reflect"
+	"testing"
+
+	"gopkg.in/telebot.v3"
+)
+
+func TestExtractURLs(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *telebot.Message
+		want []string
+	}{
+		{
+			"plain text",
+			&telebot.Message{Text: "look https://youtu.be/abc, and (https://instagram.com/p/x)."},
+			[]string{"https://youtu.be/abc", "https://instagram.com/p/x"},
+		},
+		{
+			"duplicates",
+			&telebot.Message{Text: "https://youtu.be/abc https://youtu.be/abc"},
+			[]string{"https://youtu.be/abc"},
+		},
+		{
+			"caption",
+			&telebot.Message{Caption: "from https://vm.tiktok.com/xyz/!"},
+			[]string{"https://vm.tiktok.com/xyz/"},
+		},
+		{
+			"url entity without scheme",
+			&telebot.Message{
+				Text:     "see vm.tiktok.com/xyz",
+				Entities: []telebot.MessageEntity{{Type: telebot.EntityURL, Offset: 4, Length: 17}},
+			},
+			[]string{"https://vm.tiktok.com/xyz"},
+		},
+		{
+			"text link",
+			&telebot.Message{
+				Text: "this video and https://youtu.be/abc",
+				Entities: []telebot.MessageEntity{
+					{Type: telebot.EntityTextLink, Offset: 0, Length: 10, URL: "https://youtu.be/hidden"},
+					{Type: telebot.EntityURL, Offset: 15, Length: 20},
+				},
+			},
+			[]string{"https://youtu.be/hidden", "https://youtu.be/abc"},
+		},
+		{
+			"caption entities",
+			&telebot.Message{
+				Caption:         "https://x.com/a/status/1",
+				CaptionEntities: []telebot.MessageEntity{{Type: telebot.EntityURL, Offset: 0, Length: 24}},
+			},
+			[]string{"https://x.com/a/status/1"},
+		},
+		{"no links", &telebot.Message{Text: "salom"}, nil},
+	}
+	for _, tt := range tests {
+		if got := extractURLs(tt.msg); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: extractURLs = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHandleMessageIgnoresCaptionsWithoutLinks(t *testing.T) {
+	tg, bot := setupBot(t)
+	c := bot.NewContext(telebot.Update{Message: &telebot.Message{
+		ID:      1,
+		Sender:  &telebot.User{ID: 42},
+		Chat:    &telebot.Chat{ID: 42, Type: telebot.ChatPrivate},
+		Photo:   &telebot.Photo{File: telebot.File{FileID: "photo"}},
+		Caption: "nice",
+	}})
+
+	if err := handleMessage(c); err != nil {
+		t.Fatal(err)
+	}
+	if calls := tg.methods(); len(calls) != 0 {
+		t.Errorf("answered a plain caption with %v", calls)
+	}
+	if n := len(userRequests[42]); n != 0 {
+		t.Errorf("a plain caption used %d rate limit slots", n)
+	}
+}
+
+func TestHandleMessageExplainsTextWithoutLinks(t *testing.T) {
+	tg, bot := setupBot(t)
+	if err := handleMessage(userMessage(bot, 42, "salom")); err != nil {
+		t.Fatal(err)
+	}
+	reply, _ := tg.last("sendMessage")
+	if want := catalog.T(catalog.Default(), i18n.InvalidURL); reply.Params["text"] != want {
+		t.Errorf("replied %q, want %q", reply.Params["text"], want)
+	}
+}